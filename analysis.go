@@ -0,0 +1,320 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// maxWitnesses limits the sample paths tried for a route or a pair of routes.
+const maxWitnesses = 64
+
+// Overlaps reports whether some path matches both the patterns a and b,
+// such as "/users/list" and "/users/:id". Two regular expressions are assumed to intersect.
+func Overlaps(a, b Pattern) bool {
+	return a.verb == b.verb && segmentsOverlap(splitKey(a, a.res), splitKey(b, b.res))
+}
+
+// Subsumes reports whether all the paths matching b also match a,
+// such as "/users/:id" subsumes "/users/list".
+func Subsumes(a, b Pattern) bool {
+	return a.verb == b.verb && segmentsContain(splitKey(a, a.res), splitKey(b, b.res))
+}
+
+// RoutePair is a pair of routes of the same method, which both match the Witness path.
+type RoutePair struct {
+	Winner  RouteInfo // the route serves the witness path
+	Loser   RouteInfo // the other route
+	Witness string    // a sample path matched by both
+}
+
+// Analysis is the report of the overlapping routes, see Router.Analyze.
+type Analysis struct {
+	// Ambiguous lists the pairs of routes whose winner is decided by the priority,
+	// such as "/users/list" wins "/users/:id" for the path "/users/list".
+	Ambiguous []RoutePair
+	// Unreachable lists the routes that can never be reached,
+	// the Loser is the unreachable route, the Winner serves its Witness path instead.
+	Unreachable []RoutePair
+}
+
+// Analyze reports the routes in registration order, whose paths are also matched by the other routes.
+//
+// The witness paths are sampled from the patterns and checked by the router itself,
+// the pairs without a witness path are not reported, such as the regular expressions never intersect.
+// The routes with the same pattern and different constraints or versions are decided by the request,
+// they are not reported.
+func (r *Router) Analyze() (a Analysis) {
+	segs, fresh := r.splitRoutes()
+
+	for i := range r.routes {
+		rt := &r.routes[i]
+		if rt.fallback > 0 {
+			continue // the implicit route, such as HEAD of ServeMux style's GET
+		}
+		t := r.selectTree(rt.method)
+
+		if pair, ok := r.unreachable(t, i, segs[i], fresh); ok {
+			a.Unreachable = append(a.Unreachable, pair)
+		}
+
+		for j := 0; j < i; j++ {
+			other := &r.routes[j]
+			if other.method != rt.method || other.fallback > 0 || other.key() == rt.key() ||
+				!Overlaps(rt.p, other.p) {
+				continue
+			}
+			for _, path := range witnesses(rt.p, segs[i], segs[j], fresh) {
+				if !matches(other.p, path) {
+					continue
+				}
+				var ps Params
+				served := t.match(path, &ps)
+				if served == nil || (served.key() != rt.key() && served.key() != other.key()) {
+					continue // served by neither
+				}
+				pair := RoutePair{Winner: r.routeInfo(rt), Loser: r.routeInfo(other), Witness: path}
+				if served.key() == other.key() {
+					pair.Winner, pair.Loser = pair.Loser, pair.Winner
+				}
+				a.Ambiguous = append(a.Ambiguous, pair)
+				break
+			}
+		}
+	}
+	return
+}
+
+// splitRoutes splits the keys of routes into segments,
+// and returns a segment text which is not a literal segment of the routes.
+func (r *Router) splitRoutes() (segs [][]PatternSegment, fresh string) {
+	literals := make(map[string]bool)
+	segs = make([][]PatternSegment, len(r.routes))
+	for i := range r.routes {
+		rt := &r.routes[i]
+		segs[i] = splitKey(rt.p, rt.p.res)
+		for _, seg := range segs[i] {
+			if seg.Kind == LiteralSegment {
+				literals[seg.Text] = true
+			}
+		}
+	}
+	return segs, freshSegment(literals)
+}
+
+// unreachable returns the pair if the i'th route can never be reached.
+func (r *Router) unreachable(t *tree, i int, segs []PatternSegment, fresh string) (pair RoutePair, ok bool) {
+	rt := &r.routes[i]
+	paths := witnesses(rt.p, segs, nil, fresh)
+	if len(paths) == 0 {
+		return
+	}
+
+	// the route registered with the same pattern
+	last, decided := -1, false
+	for j := range r.routes {
+		if other := &r.routes[j]; other.method == rt.method && other.key() == rt.key() {
+			if len(other.constraints) > 0 || other.version != nil {
+				decided = true
+			}
+			if last < 0 || other.fallback <= r.routes[last].fallback {
+				last = j // the same as mergeRoutes
+			}
+		}
+	}
+	if decided {
+		return
+	}
+	if last != i {
+		return RoutePair{Winner: r.routeInfo(&r.routes[last]), Loser: r.routeInfo(rt), Witness: paths[0]}, true
+	}
+
+	pair = RoutePair{Loser: r.routeInfo(rt), Witness: paths[0]}
+	for k, path := range paths {
+		var ps Params
+		served := t.match(path, &ps)
+		if served != nil && served.key() == rt.key() {
+			return pair, false
+		}
+		if k == 0 && served != nil && served.info != nil {
+			pair.Winner = *served.info
+		}
+	}
+	return pair, true
+}
+
+// witnesses returns the sample paths matched by the pattern p and the segments of other,
+// which is nil if only p is sampled.
+func witnesses(p Pattern, segs, other []PatternSegment, fresh string) []string {
+	var candidates [][]string
+	for i := 0; i < len(segs) || i < len(other); i++ {
+		var s, o *PatternSegment
+		if i < len(segs) {
+			s = &segs[i]
+		}
+		if i < len(other) {
+			o = &other[i]
+		}
+		if s != nil && s.Kind == WildcardSegment && (o == nil || o.Kind == WildcardSegment) ||
+			o != nil && o.Kind == WildcardSegment && s == nil {
+			candidates = append(candidates, []string{fresh})
+			break
+		}
+		if s == nil || s.Kind == WildcardSegment {
+			s, o = o, nil // the rest is matched by the wildcard
+		} else if o != nil && o.Kind == WildcardSegment {
+			o = nil
+		}
+		candidates = append(candidates, s.samples(o, fresh))
+	}
+
+	var paths []string
+	var b strings.Builder
+	idx := make([]int, len(candidates))
+	for len(paths) < maxWitnesses {
+		b.Reset()
+		for i, texts := range candidates {
+			if len(texts) == 0 {
+				return paths
+			}
+			b.WriteByte('/')
+			b.WriteString(texts[idx[i]])
+		}
+		if b.Len() == 0 {
+			b.WriteByte('/')
+		}
+		b.WriteString(p.verb)
+		if path := b.String(); matches(p, path) {
+			paths = append(paths, path)
+		}
+
+		// next combination
+		i := len(idx) - 1
+		for ; i >= 0; i-- {
+			if idx[i]++; idx[i] < len(candidates[i]) {
+				break
+			}
+			idx[i] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+	return paths
+}
+
+func matches(p Pattern, path string) bool {
+	_, ok := p.Match(path)
+	return ok
+}
+
+// samples returns the sample texts matching s and o, o may be nil.
+func (s *PatternSegment) samples(o *PatternSegment, fresh string) []string {
+	var texts []string
+	add := func(text string) {
+		if strings.IndexByte(text, '/') >= 0 || !s.matchString(text) || (o != nil && !o.matchString(text)) {
+			return
+		}
+		for _, t := range texts {
+			if t == text {
+				return
+			}
+		}
+		texts = append(texts, text)
+	}
+
+	for _, seg := range []*PatternSegment{s, o} {
+		if seg == nil {
+			continue
+		}
+		switch seg.Kind {
+		case LiteralSegment:
+			add(seg.Text)
+		case RegexpSegment:
+			if seg.Regexp != nil {
+				for _, text := range regexpSamples(seg.Regexp.String()) {
+					add(text)
+				}
+			}
+		}
+	}
+	add(fresh)
+	return texts
+}
+
+// freshSegment returns a segment text which is not a literal segment of the patterns.
+func freshSegment(literals map[string]bool) string {
+	text := "x"
+	for i := 1; literals[text]; i++ {
+		text = "x" + strconv.Itoa(i)
+	}
+	return text
+}
+
+// regexpSamples returns a few strings probably matched by the regular expression.
+func regexpSamples(expr string) []string {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	re = re.Simplify()
+
+	var samples []string
+	for _, more := range []bool{false, true} {
+		var b strings.Builder
+		writeRegexpSample(&b, re, more)
+		samples = append(samples, b.String())
+	}
+	return samples
+}
+
+// writeRegexpSample writes a string matched by re, the minimal one if more is false.
+func writeRegexpSample(b *strings.Builder, re *syntax.Regexp, more bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		if len(re.Rune) >= 2 {
+			if more {
+				b.WriteRune(re.Rune[len(re.Rune)-1])
+			} else {
+				b.WriteRune(re.Rune[0])
+			}
+		}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte('x')
+	case syntax.OpCapture:
+		writeRegexpSample(b, re.Sub[0], more)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writeRegexpSample(b, sub, more)
+		}
+	case syntax.OpAlternate:
+		if more {
+			writeRegexpSample(b, re.Sub[len(re.Sub)-1], more)
+		} else {
+			writeRegexpSample(b, re.Sub[0], more)
+		}
+	case syntax.OpStar, syntax.OpQuest:
+		if more {
+			writeRegexpSample(b, re.Sub[0], more)
+		}
+	case syntax.OpPlus:
+		writeRegexpSample(b, re.Sub[0], more)
+		if more {
+			writeRegexpSample(b, re.Sub[0], more)
+		}
+	case syntax.OpRepeat:
+		n := re.Min
+		if more && (re.Max < 0 || re.Max > n) {
+			n++
+		}
+		for i := 0; i < n; i++ {
+			writeRegexpSample(b, re.Sub[0], more)
+		}
+	}
+}
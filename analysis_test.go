@@ -0,0 +1,82 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		overlaps   bool
+		subsumes   bool
+		subsumedBy bool
+	}{
+		{"/users/:id", "/users/list", true, true, false},
+		{"/users/:id", "/users/:name", true, true, true},
+		{`/users/:id=^\d+$`, "/users/list", false, false, false},
+		{`/users/:id=^\d+$`, "/users/1", true, true, false},
+		{"/users/*", "/users/:id/books", true, true, false},
+		{"/a/:x/c", "/a/b/:y", true, false, false},
+		{"/a/b", "/a/b/", false, false, false},
+	}
+	for _, tt := range tests {
+		var res []*regexp.Regexp
+		a := apirouter.MustPattern(apirouter.NewPattern(tt.a, &res))
+		b := apirouter.MustPattern(apirouter.NewPattern(tt.b, &res))
+		assert.Equal(t, tt.overlaps, apirouter.Overlaps(a, b), "%s %s", tt.a, tt.b)
+		assert.Equal(t, tt.overlaps, apirouter.Overlaps(b, a), "%s %s", tt.b, tt.a)
+		assert.Equal(t, tt.subsumes, apirouter.Subsumes(a, b), "%s %s", tt.a, tt.b)
+		assert.Equal(t, tt.subsumedBy, apirouter.Subsumes(b, a), "%s %s", tt.b, tt.a)
+	}
+
+	var res []*regexp.Regexp
+	cancel := apirouter.MustPattern(apirouter.NewGRPCPattern("/jobs/{id}:cancel", &res))
+	job := apirouter.MustPattern(apirouter.NewGRPCPattern("/jobs/{id}", &res))
+	assert.False(t, apirouter.Overlaps(cancel, job))
+	assert.True(t, apirouter.Subsumes(cancel, cancel))
+}
+
+func TestAnalyze(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET("/users/list", writeString("list")),
+		apirouter.GET("/users/:id", writeString("user")),
+		apirouter.GET(`/users/:id=^\d+$`, writeString("number")),
+		apirouter.GET("/files/*path", writeString("file")),
+		apirouter.GET("/files/readme", writeString("readme")),
+		apirouter.GET("/about", writeString("about")),
+		apirouter.GET("/about", writeString("about2")),
+		apirouter.GET(`/a/:x=^c$`, writeString("x")),
+		apirouter.GET("/a/c", writeString("c")),
+		apirouter.GET("/v1/:lang", writeString("lang"), apirouter.MatchHeader("X-Lang", "go")),
+		apirouter.GET("/v1/:lang", writeString("lang")),
+		apirouter.POST("/users/list", writeString("list")),
+	)
+
+	type pair struct{ winner, loser, witness string }
+	pairs := func(rps []apirouter.RoutePair) (ps []pair) {
+		for _, rp := range rps {
+			ps = append(ps, pair{rp.Winner.Pattern.Pattern(), rp.Loser.Pattern.Pattern(), rp.Witness})
+		}
+		return
+	}
+
+	a := r.Analyze()
+	assert.Equal(t, []pair{
+		{"/users/list", "/users/:id", "/users/list"},
+		{`/users/:id=^\d+$`, "/users/:id", "/users/0"},
+		{"/files/readme", "/files/*path", "/files/readme"},
+		{"/a/c", `/a/:x=^c$`, "/a/c"},
+	}, pairs(a.Ambiguous))
+	assert.Equal(t, []pair{
+		{"/about", "/about", "/about"},
+		{"/a/c", `/a/:x=^c$`, "/a/c"},
+	}, pairs(a.Unreachable))
+}
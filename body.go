@@ -0,0 +1,36 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// DefaultMaxBodyBytes is the default limit of the request body read by the validator,
+// Transcode and Codecs.ReadRequest (so Endpoint), the larger bodies are replied with
+// 413 (Request Entity Too Large). It should be set before serving.
+var DefaultMaxBodyBytes int64 = 10 << 20
+
+// readBody reads the request body up to limit bytes, or DefaultMaxBodyBytes if limit is not positive.
+// The error is a *HTTPError with 413 (Request Entity Too Large) if the body is larger,
+// w may be nil if the connection need not be closed.
+func readBody(w http.ResponseWriter, body io.ReadCloser, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, body, limit))
+	if err != nil && int64(len(data)) >= limit { // the whole limit is read before the error
+		return data, &HTTPError{
+			Status: http.StatusRequestEntityTooLarge,
+			Code:   "body_too_large",
+			Detail: "the request body is larger than " + strconv.FormatInt(limit, 10) + " bytes",
+			Err:    err,
+		}
+	}
+	return data, err
+}
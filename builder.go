@@ -0,0 +1,161 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PatternBuilder builds the Pattern segment by segment,
+// it is the stable way for the custom PatternParser to construct
+// the key, fields and verb of pattern.
+//
+// Example, a parser of the "/users/<id>" syntax:
+//
+// 	func parse(pattern string, regexps *[]*regexp.Regexp) (apirouter.Pattern, error) {
+// 		b := apirouter.NewPatternBuilder(regexps)
+// 		for _, seg := range strings.Split(pattern[1:], "/") {
+// 			if strings.HasPrefix(seg, "<") && strings.HasSuffix(seg, ">") {
+// 				b.Param(seg[1 : len(seg)-1])
+// 			} else {
+// 				b.Literal(seg)
+// 			}
+// 		}
+// 		return b.Build(pattern)
+// 	}
+type PatternBuilder struct {
+	key      []byte
+	fields   []string
+	verb     string
+	regexps  *[]*regexp.Regexp
+	sep      byte // segment separator
+	finished bool // the pattern can not be appended any more
+	err      error
+}
+
+// NewPatternBuilder returns a new PatternBuilder,
+// "regexps" is a list of regular expressions shared between multiple patterns.
+func NewPatternBuilder(regexps *[]*regexp.Regexp) *PatternBuilder {
+	return &PatternBuilder{regexps: regexps, sep: '/'}
+}
+
+// Literal appends a segment which matches the text exactly.
+//
+// The text can not contain '/' or begin with ':' or '*',
+// the empty text is only allowed as the last segment, which is a trailing slash.
+func (b *PatternBuilder) Literal(text string) *PatternBuilder {
+	if !b.next() {
+		return b
+	}
+	if strings.IndexByte(text, b.sep) >= 0 || strings.HasPrefix(text, ":") || strings.HasPrefix(text, "*") {
+		b.fail("pattern has invalid literal segment - %q", text)
+		return b
+	}
+	b.key = append(b.key, b.sep)
+	b.key = append(b.key, text...)
+	b.finished = text == ""
+	return b
+}
+
+// Param appends a named parameter, which matches any non-empty segment.
+func (b *PatternBuilder) Param(name string) *PatternBuilder {
+	if !b.next() {
+		return b
+	}
+	b.key = append(b.key, b.sep, ':')
+	b.fields = append(b.fields, name)
+	return b
+}
+
+// RegexpParam appends a named parameter,
+// which matches the segment matched the regular expression.
+func (b *PatternBuilder) RegexpParam(name, expr string) *PatternBuilder {
+	if !b.next() {
+		return b
+	}
+	if expr == "" {
+		b.fail("pattern has empty regular expression - %q", name)
+		return b
+	}
+	rec, ok := addRegexp(b.regexps, expr)
+	if !ok {
+		b.fail("pattern has invalid regular expression - %q", expr)
+		return b
+	}
+	b.key = append(b.key, b.sep, ':', '=', rec)
+	b.fields = append(b.fields, name)
+	return b
+}
+
+// Wildcard appends a wildcard parameter, which matches the rest of path,
+// it must be the last segment.
+func (b *PatternBuilder) Wildcard(name string) *PatternBuilder {
+	if !b.next() {
+		return b
+	}
+	b.key = append(b.key, b.sep, '*')
+	b.fields = append(b.fields, name)
+	b.finished = true
+	return b
+}
+
+// Verb sets the tail static part of the pattern, such as "cancel" of "/v1/jobs/{id}:cancel".
+//
+// The router matches the verb of the URL path only if some pattern of the method has verb.
+func (b *PatternBuilder) Verb(verb string) *PatternBuilder {
+	if b.err != nil {
+		return b
+	}
+	verb = strings.TrimPrefix(verb, ":")
+	if verb == "" || strings.ContainsAny(verb, "/:") {
+		b.fail("pattern has invalid verb - %q", verb)
+		return b
+	}
+	b.verb = ":" + verb
+	return b
+}
+
+// Build returns the Pattern, the original pattern is only for display.
+// The pattern without segments is the root "/".
+func (b *PatternBuilder) Build(pattern string) (p Pattern, err error) {
+	if b.err != nil {
+		return p, b.err
+	}
+	key := make([]byte, 0, len(b.key)+len(b.verb)+1)
+	key = append(key, b.key...)
+	if len(key) == 0 {
+		key = append(key, b.sep)
+	}
+	if b.verb != "" && key[len(key)-1] == b.sep {
+		return p, fmt.Errorf("pattern with verb can not end with slash - %q", pattern)
+	}
+	key = append(key, b.verb...)
+
+	return Pattern{
+		key:     string(key),
+		fields:  append([]string(nil), b.fields...),
+		verb:    b.verb,
+		pattern: pattern,
+		res:     regexpsOf(b.regexps),
+	}, nil
+}
+
+// next checks whether a segment can be appended.
+func (b *PatternBuilder) next() bool {
+	if b.err != nil {
+		return false
+	}
+	if b.finished {
+		b.fail("pattern can not append segment after wildcard or trailing slash - %q", string(b.key))
+		return false
+	}
+	return true
+}
+
+func (b *PatternBuilder) fail(format string, args ...interface{}) {
+	b.err = fmt.Errorf(format, args...)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+// parseExpress parses the Express style's pattern, such as "/users/:id(\\d+)".
+func parseExpress(pattern string, regexps *[]*regexp.Regexp) (apirouter.Pattern, error) {
+	b := apirouter.NewPatternBuilder(regexps)
+	for _, seg := range strings.Split(pattern[1:], "/") {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			if i := strings.IndexByte(seg, '('); i > 0 && strings.HasSuffix(seg, ")") {
+				b.RegexpParam(seg[1:i], "^"+seg[i+1:len(seg)-1]+"$")
+			} else {
+				b.Param(seg[1:])
+			}
+		case seg == "*":
+			b.Wildcard("0")
+		default:
+			b.Literal(seg)
+		}
+	}
+	return b.Build(pattern)
+}
+
+// parseOpenAPI parses the OpenAPI style's pattern, such as "/users/{id}:cancel".
+func parseOpenAPI(pattern string, regexps *[]*regexp.Regexp) (apirouter.Pattern, error) {
+	b := apirouter.NewPatternBuilder(regexps)
+	path := pattern
+	if i := strings.LastIndexByte(path, ':'); i > strings.LastIndexByte(path, '/') {
+		path = pattern[:i]
+		b.Verb(pattern[i+1:])
+	}
+	for _, seg := range strings.Split(path[1:], "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			b.Param(seg[1 : len(seg)-1])
+		} else {
+			b.Literal(seg)
+		}
+	}
+	return b.Build(pattern)
+}
+
+func TestPatternStyle(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET("/about", writeString("about")),
+		apirouter.PatternStyle(parseExpress),
+		apirouter.GET(`/users/:id(\d+)`, writeString("user")),
+		apirouter.GET("/users/:name", writeString("name")),
+		apirouter.GET("/files/*", writeString("file")),
+		apirouter.PatternStyle(parseOpenAPI),
+		apirouter.POST("/jobs/{id}:cancel", writeString("cancel")),
+		apirouter.POST("/jobs/{id}", writeString("job")),
+	)
+
+	assert.Equal(t, "about", serve(r, "GET", "/about").Body.String())
+	assert.Equal(t, "user:id=1", serve(r, "GET", "/users/1").Body.String())
+	assert.Equal(t, "name:name=tom", serve(r, "GET", "/users/tom").Body.String())
+	assert.Equal(t, "file:0=a/b", serve(r, "GET", "/files/a/b").Body.String())
+	assert.Equal(t, "cancel:id=1", serve(r, "POST", "/jobs/1:cancel").Body.String())
+	assert.Equal(t, "job:id=1", serve(r, "POST", "/jobs/1").Body.String())
+
+	assert.Panics(t, func() { apirouter.PatternStyle(nil) })
+}
+
+func TestPatternStyleVerbFallback(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET("/users/:id", writeString("user")),
+		apirouter.PatternStyle(parseOpenAPI),
+		apirouter.GET("/x/{id}:do", writeString("do")),
+	)
+
+	// the paths with ':' still match the routes without the verb
+	w := serve(r, "GET", "/users/a:b")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user:id=a:b", w.Body.String())
+	assert.Equal(t, "do:id=1", serve(r, "GET", "/x/1:do").Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(r, "GET", "/x/1:undo").Code)
+
+	e := r.Explain("GET", "/users/a:b")
+	assert.Equal(t, "a:b", e.Params.ByName("id"))
+	assert.Contains(t, e.String(), "\tverb     \":b\" failed, fall back to the full path\n")
+}
+
+func TestPatternBuilder(t *testing.T) {
+	var res []*regexp.Regexp
+	p, err := apirouter.NewPatternBuilder(&res).
+		Literal("v1").Param("id").RegexpParam("n", `^\d+$`).Wildcard("path").Verb("get").
+		Build("/v1/{id}/{n}/{path=**}:get")
+	assert.NoError(t, err)
+	assert.Equal(t, "/v1/:/:=\x00/*:get", p.Key())
+	assert.Equal(t, 3, p.NumField())
+	assert.Equal(t, "path", p.Field(2))
+	assert.Equal(t, ":get", p.Verb())
+	assert.Equal(t, "/v1/{id}/{n}/{path=**}:get", p.Pattern())
+
+	// the same as the built-in parser
+	q, err := apirouter.NewGRPCPattern("/v1/{id}/{n=^\\d+$}/{path=**}:get", &res)
+	assert.NoError(t, err)
+	assert.Equal(t, q.Key(), p.Key())
+	assert.Equal(t, 1, len(res))
+
+	p, err = apirouter.NewPatternBuilder(&res).Build("")
+	assert.NoError(t, err)
+	assert.Equal(t, "/", p.Key())
+
+	errs := []*apirouter.PatternBuilder{
+		apirouter.NewPatternBuilder(&res).Literal("a/b"),
+		apirouter.NewPatternBuilder(&res).Literal(":a"),
+		apirouter.NewPatternBuilder(&res).Literal("").Literal("a"),
+		apirouter.NewPatternBuilder(&res).Wildcard("a").Param("b"),
+		apirouter.NewPatternBuilder(&res).RegexpParam("a", "("),
+		apirouter.NewPatternBuilder(&res).RegexpParam("a", ""),
+		apirouter.NewPatternBuilder(&res).Literal("a").Verb("x/y"),
+		apirouter.NewPatternBuilder(&res).Literal("").Verb("get"),
+	}
+	for i, b := range errs {
+		_, err := b.Build("")
+		assert.Error(t, err, "case %d", i)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+
+	"github.com/cnotch/apirouter"
+)
+
+// explain prints the route matched the path, the captured parameters,
+// and the other candidates with the reasons why they lost.
+func explain(w io.Writer, f *routeFile, parse apirouter.PatternParser, method, path string, trace bool) int {
+	r, err := newRouter(f, parse)
+	if err != nil {
+		fmt.Fprintf(w, "%s: %v\n", f.name, err)
+		return 1
+	}
+
+	fmt.Fprintf(w, "%s %s\n", method, path)
+	winner := -1
+	var winnerPattern apirouter.Pattern
+	if h, ps := r.Match(method, path); h != nil {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/", nil)
+		req.URL.Path = path
+		h(rec, req, ps)
+		winner, _ = strconv.Atoi(rec.Body.String())
+		winnerPattern = mustParse(f.Routes[winner], parse)
+
+		fmt.Fprintf(w, "matched: %s\n", f.Routes[winner].Pattern)
+		for i := 0; i < ps.Count(); i++ {
+			name := ps.Name(i)
+			if name == "" {
+				name = "#" + strconv.Itoa(i)
+			}
+			fmt.Fprintf(w, "\t%s = %q\n", name, ps.Value(i))
+		}
+	} else {
+		fmt.Fprintln(w, "matched: none")
+	}
+
+	for i, d := range f.Routes {
+		if i == winner || d.Method != method || validate(d, parse) != nil {
+			continue
+		}
+		p := mustParse(d, parse)
+		if _, ok := p.Match(path); !ok {
+			continue
+		}
+		reason := "the router does not backtrack after a more specific segment failed"
+		if winner >= 0 {
+			reason = lostReason(winnerPattern, p)
+		}
+		fmt.Fprintf(w, "candidate: %s\n\tlost: %s\n", d.Pattern, reason)
+	}
+
+	if trace {
+		fmt.Fprintf(w, "trace: %s", r.Explain(method, path))
+	}
+	return 0
+}
+
+func mustParse(d routeDef, parse apirouter.PatternParser) apirouter.Pattern {
+	var res []*regexp.Regexp
+	return apirouter.MustPattern(parse(d.Pattern, &res))
+}
+
+// lostReason returns why the loser lost, both match the same path.
+func lostReason(winner, loser apirouter.Pattern) string {
+	if winner.Key() == loser.Key() {
+		return "the same pattern is registered again, the last one wins"
+	}
+
+	ws, ls := winner.Segments(), loser.Segments()
+	for i := 0; i < len(ws) && i < len(ls); i++ {
+		if ws[i].Kind == ls[i].Kind {
+			continue
+		}
+		what := ws[i].Kind.String()
+		if ws[i].Kind == apirouter.LiteralSegment {
+			what += " " + strconv.Quote(ws[i].Text)
+		}
+		return fmt.Sprintf("segment %d: the %s of %s is preferred to the %s", i+1, what, winner.Pattern(), ls[i].Kind)
+	}
+	return "the more specific pattern " + winner.Pattern() + " wins"
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cnotch/apirouter"
+)
+
+// lint reports the problems of the route definitions, and returns the exit code.
+func lint(w io.Writer, f *routeFile, parse apirouter.PatternParser) int {
+	errs, warnings := 0, 0
+	for i, d := range f.Routes {
+		if err := validate(d, parse); err != nil {
+			errs++
+			fmt.Fprintf(w, "%s: route %d %q: %v\n", f.name, i+1, d.String(), err)
+		}
+	}
+
+	r, err := newRouter(f, parse)
+	if err != nil {
+		errs++
+		fmt.Fprintf(w, "%s: %v\n", f.name, err)
+	} else {
+		a := r.Analyze()
+		for _, p := range a.Unreachable {
+			errs++
+			served := "no route"
+			if p.Winner.Method != "" {
+				served = p.Winner.Pattern.Pattern()
+			}
+			fmt.Fprintf(w, "%s: %s %s: unreachable, %s is served by %s\n",
+				f.name, p.Loser.Method, p.Loser.Pattern.Pattern(), p.Witness, served)
+		}
+		for _, p := range a.Ambiguous {
+			warnings++
+			fmt.Fprintf(w, "%s: %s %s: overlaps %s, %s is served by %s\n",
+				f.name, p.Loser.Method, p.Loser.Pattern.Pattern(), p.Winner.Pattern.Pattern(),
+				p.Witness, p.Winner.Pattern.Pattern())
+		}
+	}
+
+	fmt.Fprintf(w, "%d routes, %d errors, %d warnings\n", len(f.Routes), errs, warnings)
+	if errs > 0 {
+		return 1
+	}
+	return 0
+}
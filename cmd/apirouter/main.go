@@ -0,0 +1,191 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Command apirouter lints the route definitions and explains how a path is matched.
+//
+// Usage:
+//
+// 	apirouter lint [-style default|grpc|servemux] FILE
+// 	apirouter explain [-style default|grpc|servemux] [-trace] FILE METHOD PATH
+//
+// The route definitions are in JSON or YAML, the route table saved by
+// Router.RouteTable is also accepted:
+//
+// 	style: default
+// 	routes:
+// 	  - method: GET
+// 	    pattern: /users/:id
+// 	  - pattern: GET /users/list
+//
+// The lint command validates the patterns, reports the conflicting and unreachable routes,
+// and the overlapping routes whose winner is decided by the priority. It exits with 1 if any error.
+//
+// The explain command prints the pattern matched the path, the captured parameters,
+// and the other candidates with the reasons why they lost, -trace also prints the walk of the trie.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cnotch/apirouter"
+	"gopkg.in/yaml.v2"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage:
+	apirouter lint [-style default|grpc|servemux] FILE
+	apirouter explain [-style default|grpc|servemux] [-trace] FILE METHOD PATH
+`
+
+// run runs the command and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	style := fs.String("style", "", "pattern style: default, grpc or servemux, overrides the style of the file")
+	trace := fs.Bool("trace", false, "print the walk of the trie, only for explain")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	var nargs int
+	switch args[0] {
+	case "lint":
+		nargs = 1
+	case "explain":
+		nargs = 3
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if fs.NArg() != nargs {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	f, err := loadRouteFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *style != "" {
+		f.Style = *style
+	}
+	parse, ok := parsers[f.Style]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown pattern style %q\n", f.Style)
+		return 2
+	}
+
+	if args[0] == "lint" {
+		return lint(stdout, f, parse)
+	}
+	return explain(stdout, f, parse, strings.ToUpper(fs.Arg(1)), fs.Arg(2), *trace)
+}
+
+// routeFile is the route definitions.
+type routeFile struct {
+	name   string
+	Style  string     `yaml:"style"`
+	Routes []routeDef `yaml:"routes"`
+}
+
+// routeDef is a route definition, the method can be the prefix of the pattern, such as "GET /users".
+type routeDef struct {
+	Method  string `yaml:"method"`
+	Pattern string `yaml:"pattern"`
+}
+
+func (d routeDef) String() string {
+	return d.Method + " " + d.Pattern
+}
+
+var parsers = map[string]apirouter.PatternParser{
+	"":         apirouter.NewPattern,
+	"default":  apirouter.NewPattern,
+	"grpc":     apirouter.NewGRPCPattern,
+	"servemux": apirouter.NewServeMuxPattern,
+}
+
+var methods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodDelete: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodHead: true,
+	http.MethodConnect: true, http.MethodTrace: true, http.MethodOptions: true,
+}
+
+// loadRouteFile loads the route definitions in JSON or YAML.
+func loadRouteFile(name string) (f *routeFile, err error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f = &routeFile{name: name}
+	if err = yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	for i := range f.Routes {
+		d := &f.Routes[i]
+		if d.Method == "" {
+			if sp := strings.IndexByte(d.Pattern, ' '); sp > 0 {
+				d.Method, d.Pattern = d.Pattern[:sp], strings.TrimSpace(d.Pattern[sp+1:])
+			}
+		}
+		d.Method = strings.ToUpper(d.Method)
+	}
+	return
+}
+
+// validate checks the route definition.
+func validate(d routeDef, parse apirouter.PatternParser) error {
+	if !methods[d.Method] {
+		return fmt.Errorf("unknown method %q", d.Method)
+	}
+	var res []*regexp.Regexp
+	_, err := parse(d.Pattern, &res)
+	return err
+}
+
+// newRouter returns the router of the valid routes, the handler of route
+// writes its index in the route definitions.
+func newRouter(f *routeFile, parse apirouter.PatternParser) (r *apirouter.Router, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = errors.New(strings.TrimPrefix(fmt.Sprint(v), "router: "))
+		}
+	}()
+
+	var options []apirouter.Option
+	if f.Style != "servemux" {
+		options = append(options, apirouter.PatternStyle(parse))
+	}
+	for i, d := range f.Routes {
+		if validate(d, parse) != nil {
+			continue
+		}
+		i := i
+		options = append(options, apirouter.API(d.Method, d.Pattern,
+			func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+				fmt.Fprint(w, i)
+			}))
+	}
+	if f.Style == "servemux" {
+		return apirouter.NewForServeMux(options...), nil
+	}
+	return apirouter.New(options...), nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+const routesYAML = `
+routes:
+  - method: GET
+    pattern: /users/list
+  - method: get
+    pattern: /users/:id
+  - pattern: GET /users/:id=^\d+$/books
+  - pattern: GET /about
+  - pattern: GET /about
+  - pattern: POST /users/:id/*
+  - pattern: PUSH /users
+  - pattern: GET /files/*path/raw
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	name = filepath.Join(t.TempDir(), name)
+	assert.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+	return name
+}
+
+func runCmd(args ...string) (int, string) {
+	var out bytes.Buffer
+	code := run(args, &out, &out)
+	return code, out.String()
+}
+
+func TestLint(t *testing.T) {
+	name := writeFile(t, "routes.yaml", routesYAML)
+	code, out := runCmd("lint", name)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, name+`: route 7 "PUSH /users": unknown method "PUSH"
+`+name+`: route 8 "GET /files/*path/raw": '*' in pattern must is last segment - "/files/*path/raw"
+`+name+`: GET /about: unreachable, /about is served by /about
+`+name+`: GET /users/:id: overlaps /users/list, /users/list is served by /users/list
+8 routes, 3 errors, 1 warnings
+`, out)
+
+	name = writeFile(t, "routes.json", `{"style":"grpc","routes":[
+		{"method":"GET","pattern":"/v1/{name=shelves/**/books}"},
+		{"method":"GET","pattern":"/v1/shelves/{id}:get"}]}`)
+	code, out = runCmd("lint", name)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "1 errors")
+
+	name = writeFile(t, "routes.json", `{"routes":[
+		{"method":"GET","pattern":"/a/{x}"},
+		{"method":"GET","pattern":"/{y}/b"}]}`)
+	code, out = runCmd("lint", "-style", "servemux", name)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, `pattern "/{y}/b" conflicts with pattern "/a/{x}"`)
+
+	code, out = runCmd("lint", "-style", "servemux", writeFile(t, "routes.json", `{"routes":[
+		{"method":"GET","pattern":"/items/{id}"},
+		{"method":"GET","pattern":"/items/new"}]}`))
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "2 routes, 0 errors, 1 warnings")
+
+	code, _ = runCmd("lint")
+	assert.Equal(t, 2, code)
+	code, _ = runCmd("lint", "-style", "express", name)
+	assert.Equal(t, 2, code)
+}
+
+func TestExplain(t *testing.T) {
+	name := writeFile(t, "routes.yaml", routesYAML)
+	code, out := runCmd("explain", name, "GET", "/users/list")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, `GET /users/list
+matched: /users/list
+candidate: /users/:id
+	lost: segment 2: the literal "list" of /users/list is preferred to the parameter
+`, out)
+
+	_, out = runCmd("explain", name, "get", "/users/1/books")
+	assert.Equal(t, `GET /users/1/books
+matched: /users/:id=^\d+$/books
+	id = "1"
+`, out)
+
+	_, out = runCmd("explain", name, "GET", "/about")
+	assert.Contains(t, out, "lost: the same pattern is registered again, the last one wins")
+
+	_, out = runCmd("explain", name, "GET", "/users/x/books")
+	assert.Equal(t, "GET /users/x/books\nmatched: none\n", out)
+
+	_, out = runCmd("explain", "-trace", name, "GET", "/users/x/books")
+	assert.Contains(t, out, "trace: GET /users/x/books\n")
+	assert.Contains(t, out, "\tregexp   \"x\" failed, ^\\d+$\n")
+}
+
+func TestLoadRouteTable(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET("/users/:id", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {}),
+	)
+	data, err := json.Marshal(r.RouteTable())
+	assert.NoError(t, err)
+
+	f, err := loadRouteFile(writeFile(t, "routes.json", string(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, []routeDef{{Method: "GET", Pattern: "/users/:id"}}, f.Routes)
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Codec encodes and decodes the request and response bodies of a media type,
+// such as JSON, XML, msgpack or protobuf.
+type Codec interface {
+	// MediaType returns the media type, such as "application/json".
+	MediaType() string
+	// Marshal returns the encoding of v.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal parses the encoded data and stores the result in the value pointed to by v.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSONCodec is the "application/json" codec using encoding/json.
+	JSONCodec Codec = jsonCodec{}
+	// XMLCodec is the "application/xml" codec using encoding/xml.
+	XMLCodec Codec = xmlCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) MediaType() string                          { return "application/json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type xmlCodec struct{}
+
+func (xmlCodec) MediaType() string                          { return "application/xml" }
+func (xmlCodec) Marshal(v interface{}) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+// Codecs is a registry of the codecs keyed by media type, in the order of preference.
+// It is safe for concurrent use.
+type Codecs struct {
+	mu     sync.RWMutex
+	codecs []Codec
+}
+
+// DefaultCodecs is the registry used by Endpoint by default, which has JSONCodec only.
+var DefaultCodecs = NewCodecs(JSONCodec)
+
+// NewCodecs returns a new registry of the codecs, the first one is preferred.
+func NewCodecs(codecs ...Codec) *Codecs {
+	cs := &Codecs{}
+	for _, c := range codecs {
+		cs.Register(c)
+	}
+	return cs
+}
+
+// Register registers the codec, which replaces the one of the same media type,
+// or is the least preferred if the media type is new.
+func (cs *Codecs) Register(c Codec) {
+	if c == nil {
+		panic("router: nil codec")
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	// copy on write, the readers use the slice without the lock
+	codecs := make([]Codec, len(cs.codecs), len(cs.codecs)+1)
+	copy(codecs, cs.codecs)
+	mt := parseMediaRange(c.MediaType())
+	for i, old := range codecs {
+		if sameMediaType(parseMediaRange(old.MediaType()), mt) {
+			codecs[i] = c
+			cs.codecs = codecs
+			return
+		}
+	}
+	cs.codecs = append(codecs, c)
+}
+
+// Lookup returns the codec of the media type, nil if not registered.
+// The parameters such as charset are ignored, eg "application/json; charset=utf-8".
+func (cs *Codecs) Lookup(mediaType string) Codec {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	mt := parseMediaRange(mediaType)
+	for _, c := range cs.codecs {
+		if sameMediaType(parseMediaRange(c.MediaType()), mt) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Negotiate returns the codec preferred by the request Accept header, nil if none is acceptable.
+// The q-values and the wildcards are supported, the more preferred codec wins the ties.
+//
+// If the route declares the media types by Produces, only their codecs are negotiated.
+func (cs *Codecs) Negotiate(r *http.Request) Codec {
+	c, _ := cs.negotiate(r)
+	return c
+}
+
+// negotiate returns the negotiated codec and the number of candidates.
+func (cs *Codecs) negotiate(r *http.Request) (Codec, int) {
+	cs.mu.RLock()
+	candidates := cs.codecs
+	cs.mu.RUnlock()
+
+	if produces, ok := r.Context().Value(producesKey).([]mediaRange); ok {
+		filtered := make([]Codec, 0, len(candidates))
+		for _, c := range candidates {
+			mt := parseMediaRange(c.MediaType())
+			for _, rg := range produces {
+				if rg.includes(mt) {
+					filtered = append(filtered, c)
+					break
+				}
+			}
+		}
+		candidates = filtered
+	}
+	if len(candidates) == 0 {
+		return nil, 0
+	}
+	return negotiateCodec(r.Header.Get("Accept"), candidates), len(candidates)
+}
+
+// ReadRequest decodes the request body into v by the codec of the Content-Type,
+// or by the preferred codec if the Content-Type is absent. The empty body is ignored.
+//
+// The error is a *HTTPError, with 415 (Unsupported Media Type) if no codec is registered
+// for the Content-Type, with 413 (Request Entity Too Large) if the body is larger than
+// DefaultMaxBodyBytes, or with 400 (Bad Request) if the body is invalid.
+func (cs *Codecs) ReadRequest(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	data, err := readBody(nil, r.Body, 0)
+	if he, ok := err.(*HTTPError); ok {
+		return he
+	}
+	if err != nil {
+		return invalidRequest("cannot read body", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var c Codec
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		c = cs.Lookup(ct)
+	} else {
+		cs.mu.RLock()
+		if len(cs.codecs) > 0 {
+			c = cs.codecs[0]
+		}
+		cs.mu.RUnlock()
+	}
+	if c == nil {
+		return &HTTPError{Status: http.StatusUnsupportedMediaType, Code: "unsupported_media_type"}
+	}
+	if err = c.Unmarshal(data, v); err != nil {
+		return invalidRequest("invalid body", err)
+	}
+	return nil
+}
+
+// WriteResponse writes the response v with the status, encoded by the negotiated codec.
+//
+// The error is a *HTTPError with 406 (Not Acceptable) if no codec is acceptable,
+// or the error of Marshal. Nothing is written in both cases.
+func (cs *Codecs) WriteResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	c, n := cs.negotiate(r)
+	if c == nil {
+		return errNotAcceptable
+	}
+	return writeCodec(w, c, n > 1, status, v)
+}
+
+var errNotAcceptable = &HTTPError{Status: http.StatusNotAcceptable, Code: "not_acceptable"}
+
+// writeCodec writes the response encoded by the codec,
+// vary reports whether the codec is negotiated among several codecs.
+func writeCodec(w http.ResponseWriter, c Codec, vary bool, status int, v interface{}) error {
+	data, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if vary {
+		w.Header().Add("Vary", "Accept")
+	}
+	w.Header().Set("Content-Type", c.MediaType())
+	w.WriteHeader(status)
+	w.Write(data)
+	return nil
+}
+
+func invalidRequest(detail string, err error) error {
+	return &HTTPError{Status: http.StatusBadRequest, Code: "invalid_request", Detail: detail, Err: err}
+}
+
+// negotiateCodec returns the codec preferred by the Accept header, nil if none is acceptable.
+// The first codec is preferred if the Accept header is absent or the q-values are equal.
+func negotiateCodec(accept string, codecs []Codec) Codec {
+	if accept == "" {
+		return codecs[0]
+	}
+
+	ranges := parseAccept(accept)
+	var best Codec
+	var bestQ float64
+	for _, c := range codecs {
+		if q := acceptQuality(ranges, parseMediaRange(c.MediaType())); q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the q-value of the most specific range including the media type.
+func acceptQuality(ranges []mediaRange, mt mediaRange) (q float64) {
+	specificity := -1
+	for _, rg := range ranges {
+		if !rg.includes(mt) {
+			continue
+		}
+		if s := rg.specificity(); s > specificity {
+			specificity, q = s, rg.q
+		}
+	}
+	return
+}
+
+// specificity ranks "*/*" < "type/*" < "type/subtype" < "type/subtype;param=value".
+func (mr mediaRange) specificity() int {
+	switch {
+	case mr.typ == "*":
+		return 0
+	case mr.subtype == "*":
+		return 1
+	}
+	return 2 + len(mr.params)
+}
+
+func sameMediaType(a, b mediaRange) bool {
+	return a.typ == b.typ && a.subtype == b.subtype
+}
+
+// Consumes creates the route option to declare the media types of the request body,
+// such as "application/json". The request with a body of the other media types
+// is replied 415 (Unsupported Media Type), the request without body is allowed.
+//
+// The media types are also documented in the OpenAPI document.
+func Consumes(mediaTypes ...string) RouteOption {
+	consumes := parseMediaTypes(mediaTypes)
+	c := NewConstraint(func(r *http.Request) bool {
+		ct := r.Header.Get("Content-Type")
+		if ct == "" {
+			return r.ContentLength == 0
+		}
+		mt := parseMediaRange(ct)
+		if !mt.concrete() {
+			return false
+		}
+		for _, rg := range consumes {
+			if rg.includes(mt) {
+				return true
+			}
+		}
+		return false
+	}, http.StatusUnsupportedMediaType)
+	c.desc = "content-type " + strings.Join(mediaTypes, ",")
+	c.meta = true
+
+	return routeOptionFunc(func(rt *route) {
+		rt.constraints = append(rt.constraints, c)
+		rt.consumes = append(rt.consumes, mediaTypes...)
+	})
+}
+
+// Produces creates the route option to declare the media types of the response,
+// such as "application/json". The request which accepts none of them
+// is replied 406 (Not Acceptable), see MatchAccept.
+//
+// Codecs.Negotiate and Codecs.WriteResponse only negotiate the codecs of the media types,
+// which are also documented in the OpenAPI document.
+func Produces(mediaTypes ...string) RouteOption {
+	accept := MatchAccept(mediaTypes...)
+	return routeOptionFunc(func(rt *route) {
+		accept.applyRoute(rt)
+		rt.produces = append(rt.produces, mediaTypes...)
+	})
+}
+
+type producesKeyType struct{}
+
+var producesKey = producesKeyType{}
+
+// producesCtx carries the media types produced by the route to Codecs.
+type producesCtx struct {
+	context.Context
+	produces []mediaRange
+}
+
+func (c *producesCtx) Value(key interface{}) interface{} {
+	if key == producesKey {
+		return c.produces
+	}
+	return c.Context.Value(key)
+}
+
+// wrapProduces wraps the handler to store the media types produced in the request context.
+func wrapProduces(h Handler, mediaTypes []string) Handler {
+	produces := parseMediaTypes(mediaTypes)
+	return func(w http.ResponseWriter, r *http.Request, ps Params) {
+		h(w, r.WithContext(&producesCtx{Context: r.Context(), produces: produces}), ps)
+	}
+}
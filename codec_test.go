@@ -0,0 +1,164 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecs(t *testing.T) {
+	cs := apirouter.NewCodecs(apirouter.JSONCodec, apirouter.XMLCodec)
+	assert.Equal(t, apirouter.JSONCodec, cs.Lookup("application/json; charset=utf-8"))
+	assert.Nil(t, cs.Lookup("text/plain"))
+	cs.Register(textCodec{})
+	assert.Equal(t, textCodec{}, cs.Lookup("TEXT/PLAIN"))
+
+	tests := []struct {
+		accept string
+		want   apirouter.Codec
+	}{
+		{"", apirouter.JSONCodec},
+		{"*/*", apirouter.JSONCodec},
+		{"application/xml", apirouter.XMLCodec},
+		{"application/*, application/json;q=0.5", apirouter.XMLCodec},
+		{"text/*;q=0.9, */*;q=0.8", textCodec{}},
+		{"text/html, application/xml;q=0", nil},
+		{"application/json; charset=utf-8", apirouter.JSONCodec},
+		{"application/xml; charset=UTF-8; q=0.9, application/json; q=0.5", apirouter.XMLCodec},
+		{"application/json; version=2", apirouter.JSONCodec},
+		{"image/*", nil},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", tt.accept)
+		assert.Equal(t, tt.want, cs.Negotiate(req), tt.accept)
+	}
+}
+
+func TestCodecsConcurrentRegister(t *testing.T) {
+	cs := apirouter.NewCodecs(apirouter.JSONCodec, apirouter.XMLCodec)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			cs.Register(apirouter.XMLCodec)
+			cs.Register(textCodec{})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(`{"id":1}`))
+			req.Header.Set("Accept", "application/xml")
+			var it item
+			assert.NoError(t, cs.ReadRequest(req, &it))
+			assert.NoError(t, cs.WriteResponse(httptest.NewRecorder(), req, http.StatusOK, it))
+		}
+	}()
+	wg.Wait()
+}
+
+func TestCodecsHelpers(t *testing.T) {
+	cs := apirouter.NewCodecs(apirouter.JSONCodec, apirouter.XMLCodec)
+	h := func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+		var it item
+		if err := cs.ReadRequest(r, &it); err != nil {
+			apirouter.WriteProblem(w, r, err)
+			return
+		}
+		if err := cs.WriteResponse(w, r, http.StatusCreated, it); err != nil {
+			apirouter.WriteProblem(w, r, err)
+		}
+	}
+	r := apirouter.New(
+		apirouter.POST("/items", h,
+			apirouter.Consumes("application/json", "application/xml"),
+			apirouter.Produces("application/json"),
+			apirouter.RequestType(item{}),
+			apirouter.ResponseType(http.StatusCreated, item{})),
+		apirouter.POST("/any", h),
+	)
+
+	post := func(path, contentType, accept, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/items", "application/xml", "", `<item><id>1</id><name>pen</name></item>`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"id":1,"name":"pen"}`, w.Body.String())
+
+	// only the produced media types are negotiated
+	w = post("/items", "application/json", "application/xml, */*;q=0.1", `{"id":2}`)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Vary"))
+	w = post("/any", "application/json", "application/xml, */*;q=0.1", `{"id":2}`)
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	// automatic 415 and 406 by the route metadata
+	assert.Equal(t, http.StatusUnsupportedMediaType, post("/items", "text/plain", "", "pen").Code)
+	assert.Equal(t, http.StatusNotAcceptable, post("/items", "application/json", "application/xml", `{}`).Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, post("/items", "*/*", "", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, post("/items", "application/json", "application/json; charset=utf-8", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, post("/items", "", "", "").Code)
+
+	// replied by the helpers
+	w = post("/any", "text/plain", "", "pen")
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), `"unsupported_media_type"`)
+	w = post("/any", "application/json", "text/plain", `{}`)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	w = post("/any", "application/json", "", `{"id":`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	limit := apirouter.DefaultMaxBodyBytes
+	apirouter.DefaultMaxBodyBytes = 8
+	w = post("/any", "application/json", "", `{"id":1234}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), `"body_too_large"`)
+	assert.Equal(t, http.StatusCreated, post("/any", "application/json", "", `{"id":1}`).Code)
+	apirouter.DefaultMaxBodyBytes = limit
+
+	for _, info := range r.Routes() {
+		if info.Pattern.Pattern() == "/items" {
+			assert.Equal(t, []string{"application/json", "application/xml"}, info.Consumes)
+			assert.Equal(t, []string{"application/json"}, info.Produces)
+		}
+	}
+	op := r.OpenAPI(apirouter.Info{}).Paths["/items"].Post
+	assert.Equal(t, 2, len(op.RequestBody.Content))
+	assert.NotNil(t, op.RequestBody.Content["application/xml"])
+	assert.NotNil(t, op.Responses["201"].Content["application/json"])
+}
+
+func TestEndpointProduces(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET("/items/:id", apirouter.Endpoint(func(ctx context.Context, req getItemRequest) (item, error) {
+			return item{ID: req.ID}, nil
+		}, apirouter.EndpointCodecs(apirouter.JSONCodec, apirouter.XMLCodec)),
+			apirouter.Produces("application/xml")),
+	)
+	w := serve(r, "GET", "/items/1")
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+	w = serve(r, "GET", "/items/1", "Accept", "application/json")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
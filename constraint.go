@@ -0,0 +1,379 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Constraint is an additional condition, besides the path,
+// that a request must satisfy to be dispatched to a route.
+type Constraint struct {
+	match  func(r *http.Request) bool
+	status int        // reply status when no route satisfies the constraints
+	param  *Parameter // the parameter documented in OpenAPI, nil if none
+	desc   string     // description of the built-in constraint
+	meta   bool       // implied by the route metadata, such as Consumes
+}
+
+// Match reports whether the request satisfies the constraint.
+func (c Constraint) Match(r *http.Request) bool { return c.match(r) }
+
+// Status returns the status code replied when the path matched,
+// but the request does not satisfy the constraint.
+func (c Constraint) Status() int { return c.status }
+
+// String returns the description of the constraint, such as "header X-Lang=go",
+// or "func" if it is created by NewConstraint or MatchFunc.
+func (c Constraint) String() string {
+	if c.desc == "" {
+		return "func"
+	}
+	return c.desc
+}
+
+// NewConstraint returns a new Constraint, the status is replied
+// when the path matched but no route's constraints are satisfied.
+func NewConstraint(match func(r *http.Request) bool, status int) Constraint {
+	if match == nil {
+		panic("router: nil constraint")
+	}
+	return Constraint{match: match, status: status}
+}
+
+// Constraints creates the route option to attach the constraints to the route.
+//
+// Several routes with the same pattern may be registered for the same method,
+// the first whose constraints are all satisfied by the request wins,
+// the routes without constraints are tried last.
+func Constraints(cs ...Constraint) RouteOption {
+	return routeOptionFunc(func(rt *route) {
+		rt.constraints = append(rt.constraints, cs...)
+	})
+}
+
+// MatchHeader creates the route option which requires the
+// request header is equal to the given value.
+func MatchHeader(name, value string) RouteOption {
+	c := NewConstraint(func(r *http.Request) bool {
+		return r.Header.Get(name) == value
+	}, http.StatusNotFound)
+	c.param = &Parameter{Name: name, In: "header", Required: true,
+		Schema: &Schema{Type: "string", Enum: []interface{}{value}}}
+	c.desc = "header " + name + "=" + value
+	return Constraints(c)
+}
+
+// MatchHeaderRegexp creates the route option which requires the
+// request header matches the regular expression.
+func MatchHeaderRegexp(name, expr string) RouteOption {
+	re := regexp.MustCompile(expr)
+	c := NewConstraint(func(r *http.Request) bool {
+		return re.MatchString(r.Header.Get(name))
+	}, http.StatusNotFound)
+	c.param = &Parameter{Name: name, In: "header", Required: true,
+		Schema: &Schema{Type: "string", Pattern: expr}}
+	c.desc = "header " + name + "=~" + expr
+	return Constraints(c)
+}
+
+// MatchQuery creates the route option which requires the
+// request query parameter is present, and if values are given,
+// the parameter is equal to one of them.
+func MatchQuery(name string, values ...string) RouteOption {
+	schema := &Schema{Type: "string"}
+	for _, v := range values {
+		schema.Enum = append(schema.Enum, v)
+	}
+	c := NewConstraint(func(r *http.Request) bool {
+		vs, ok := r.URL.Query()[name]
+		if !ok {
+			return false
+		}
+		if len(values) == 0 {
+			return true
+		}
+		for _, v := range vs {
+			for _, value := range values {
+				if v == value {
+					return true
+				}
+			}
+		}
+		return false
+	}, http.StatusNotFound)
+	c.param = &Parameter{Name: name, In: "query", Required: true, Schema: schema}
+	c.desc = "query " + name
+	if len(values) > 0 {
+		c.desc += "=" + strings.Join(values, "|")
+	}
+	return Constraints(c)
+}
+
+// MatchContentType creates the route option which requires the
+// request Content-Type is one of the given media types (eg "application/json", "multipart/*").
+// Otherwise, 415 Unsupported Media Type is replied, so is the Content-Type with wildcards, eg "*/*".
+func MatchContentType(mediaTypes ...string) RouteOption {
+	ranges := parseMediaTypes(mediaTypes)
+	c := NewConstraint(func(r *http.Request) bool {
+		mt := parseMediaRange(r.Header.Get("Content-Type"))
+		if !mt.concrete() {
+			return false
+		}
+		for _, rg := range ranges {
+			if rg.includes(mt) {
+				return true
+			}
+		}
+		return false
+	}, http.StatusUnsupportedMediaType)
+	c.desc = "content-type " + strings.Join(mediaTypes, ",")
+	return Constraints(c)
+}
+
+// MatchAccept creates the route option which requires the
+// request Accept header accepts one of the given media types.
+// Otherwise, 406 Not Acceptable is replied.
+//
+// A request without the Accept header accepts any media types.
+func MatchAccept(mediaTypes ...string) RouteOption {
+	produces := parseMediaTypes(mediaTypes)
+	c := NewConstraint(func(r *http.Request) bool {
+		accept := r.Header.Get("Accept")
+		if accept == "" {
+			return true
+		}
+		for _, rg := range parseAccept(accept) {
+			if rg.q <= 0 {
+				continue
+			}
+			for _, mt := range produces {
+				if rg.includes(mt) {
+					return true
+				}
+			}
+		}
+		return false
+	}, http.StatusNotAcceptable)
+	c.desc = "accept " + strings.Join(mediaTypes, ",")
+	return Constraints(c)
+}
+
+// MatchFunc creates the route option which requires the
+// request satisfies the given function.
+func MatchFunc(match func(r *http.Request) bool) RouteOption {
+	return Constraints(NewConstraint(match, http.StatusNotFound))
+}
+
+// mergeRoutes merges the routes with the same key.
+//
+// If no route has constraints or version, the last registered wins,
+// otherwise the merged route dispatches the request to the first
+// route whose constraints are satisfied, see also ForVersion.
+// The fallback routes are less preferred in both cases.
+func mergeRoutes(routes []route, router *Router) route {
+	conditional, versioned := false, false
+	last := -1
+	for i, rt := range routes {
+		if len(rt.constraints) > 0 {
+			conditional = true
+		}
+		if rt.version != nil {
+			versioned = true
+		}
+		if last < 0 || rt.fallback <= routes[last].fallback {
+			last = i
+		}
+	}
+	if !conditional && !versioned {
+		return routes[last]
+	}
+
+	candidates := append([]route(nil), routes...)
+	sortCandidates(candidates)
+	merged := routes[0]
+	merged.constraints = nil
+	merged.version = nil
+	merged.candidates = candidates
+	merged.p.spans = nil // bound by the candidate
+	merged.h = func(w http.ResponseWriter, r *http.Request, ps Params) {
+		status := http.StatusNotFound
+		var requested *Version
+		if versioned {
+			var ok bool
+			if requested, ok = router.requestedVersion(r); !ok {
+				router.reject(w, r, http.StatusBadRequest)
+				return
+			}
+		}
+		for i := range candidates {
+			c := &candidates[i]
+			if c.version != nil && requested != nil && requested.Less(*c.version) {
+				continue // newer than requested
+			}
+			if failed := c.unsatisfied(r); failed != 0 {
+				status = rejectStatus(status, failed)
+				continue
+			}
+			if c.version != nil {
+				router.markVersion(w, *c.version)
+			}
+			ps.names = c.p.fields
+			c.p.bindSpans(&ps)
+			setCurrentRoute(r, c.info)
+			c.h(w, r, ps)
+			return
+		}
+		router.reject(w, r, status)
+	}
+	return merged
+}
+
+// sortCandidates sorts the routes in descending order of version,
+// and then the routes with constraints are placed before the others,
+// the fallback routes are placed last.
+func sortCandidates(routes []route) {
+	rank := func(rt *route) int {
+		r := int(rt.fallback) << 1
+		if len(rt.constraints) == 0 {
+			r++
+		}
+		return r
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		vi, vj := routes[i].version, routes[j].version
+		if (vi == nil) != (vj == nil) {
+			return vi != nil
+		}
+		if vi != nil && *vi != *vj {
+			return vj.Less(*vi)
+		}
+		return rank(&routes[i]) < rank(&routes[j])
+	})
+}
+
+// unsatisfied returns the status of the first constraint not satisfied,
+// or 0 if all constraints are satisfied.
+func (rt *route) unsatisfied(r *http.Request) int {
+	for _, c := range rt.constraints {
+		if !c.match(r) {
+			if c.status == 0 {
+				return http.StatusNotFound
+			}
+			return c.status
+		}
+	}
+	return 0
+}
+
+// rejectStatus prefers 415, then 406, then the others.
+func rejectStatus(curr, status int) int {
+	rank := func(s int) int {
+		switch s {
+		case http.StatusUnsupportedMediaType:
+			return 3
+		case http.StatusNotAcceptable:
+			return 2
+		case http.StatusNotFound:
+			return 0
+		default:
+			return 1
+		}
+	}
+	if rank(status) > rank(curr) {
+		return status
+	}
+	return curr
+}
+
+// mediaRange is a parsed media type or media range, eg "text/*;q=0.8".
+type mediaRange struct {
+	typ, subtype string
+	params       map[string]string
+	q            float64
+}
+
+func parseMediaRange(s string) (mr mediaRange) {
+	mr.q = 1
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		for _, param := range strings.Split(s[i+1:], ";") {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			k := strings.ToLower(strings.TrimSpace(kv[0]))
+			v := strings.Trim(strings.TrimSpace(kv[1]), `"`)
+			if k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					mr.q = q
+				}
+				continue
+			}
+			if mr.params == nil {
+				mr.params = make(map[string]string)
+			}
+			mr.params[k] = v
+		}
+		s = s[:i]
+	}
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		mr.typ, mr.subtype = s[:i], s[i+1:]
+	} else {
+		mr.typ, mr.subtype = s, "*"
+	}
+	return
+}
+
+func parseMediaTypes(mediaTypes []string) []mediaRange {
+	ranges := make([]mediaRange, len(mediaTypes))
+	for i, mt := range mediaTypes {
+		ranges[i] = parseMediaRange(mt)
+	}
+	return ranges
+}
+
+// parseAccept parses the value of Accept header.
+func parseAccept(accept string) []mediaRange {
+	parts := strings.Split(accept, ",")
+	ranges := make([]mediaRange, 0, len(parts))
+	for _, part := range parts {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		ranges = append(ranges, parseMediaRange(part))
+	}
+	return ranges
+}
+
+// includes reports whether the media range includes the media type.
+// Only the parameters specified by both are compared, eg "application/json; charset=utf-8"
+// includes "application/json", the charset is compared case-insensitively.
+func (mr mediaRange) includes(mt mediaRange) bool {
+	if mr.typ != "*" && mt.typ != "*" && mr.typ != mt.typ {
+		return false
+	}
+	if mr.subtype != "*" && mt.subtype != "*" && mr.subtype != mt.subtype {
+		return false
+	}
+	for k, v := range mr.params {
+		mv, ok := mt.params[k]
+		if !ok || mv == v || (k == "charset" && strings.EqualFold(mv, v)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// concrete reports whether it is a media type without wildcards,
+// as required for the Content-Type of a request body.
+func (mr mediaRange) concrete() bool {
+	return mr.typ != "" && mr.typ != "*" && mr.subtype != "*"
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeString(s string) apirouter.Handler {
+	return func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+		io.WriteString(w, s)
+		for i := 0; i < ps.Count(); i++ {
+			io.WriteString(w, ":"+ps.Name(i)+"="+ps.Value(i))
+		}
+	}
+}
+
+func serve(h http.Handler, method, path string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRouterConstraints(t *testing.T) {
+	r := apirouter.New(
+		apirouter.POST("/files/:id", writeString("json"),
+			apirouter.MatchContentType("application/json")),
+		apirouter.POST("/files/:name", writeString("upload"),
+			apirouter.MatchContentType("multipart/*")),
+		apirouter.GET("/items/:id", writeString("v2"),
+			apirouter.MatchAccept("application/vnd.v2+json")),
+		apirouter.GET("/items/:id", writeString("v1"),
+			apirouter.MatchAccept("application/json")),
+		apirouter.GET("/media", writeString("media"),
+			apirouter.MatchQuery("alt", "media")),
+		apirouter.GET("/media", writeString("meta"),
+			apirouter.MatchHeaderRegexp("X-Meta", `^\d+$`)),
+		apirouter.GET("/media", writeString("default")),
+		apirouter.GET("/beta", writeString("beta"),
+			apirouter.MatchHeader("X-Beta", "on")),
+	)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header []string
+		status int
+		body   string
+	}{
+		{"json", "POST", "/files/1", []string{"Content-Type", "application/json; charset=utf-8"}, 200, "json:id=1"},
+		{"upload", "POST", "/files/1", []string{"Content-Type", "multipart/form-data; boundary=x"}, 200, "upload:name=1"},
+		{"unsupported", "POST", "/files/1", []string{"Content-Type", "text/plain"}, 415, ""},
+		{"no content-type", "POST", "/files/1", nil, 415, ""},
+		{"wildcard content-type", "POST", "/files/1", []string{"Content-Type", "*/*"}, 415, ""},
+		{"partial wildcard content-type", "POST", "/files/1", []string{"Content-Type", "multipart/*"}, 415, ""},
+		{"accept v2", "GET", "/items/7", []string{"Accept", "application/vnd.v2+json"}, 200, "v2:id=7"},
+		{"accept v1", "GET", "/items/7", []string{"Accept", "application/json"}, 200, "v1:id=7"},
+		{"accept charset", "GET", "/items/7", []string{"Accept", "application/json; charset=utf-8"}, 200, "v1:id=7"},
+		{"accept params", "GET", "/items/7", []string{"Accept", "application/vnd.v2+json; charset=UTF-8; q=0.9"}, 200, "v2:id=7"},
+		{"accept any", "GET", "/items/7", []string{"Accept", "*/*"}, 200, "v2:id=7"},
+		{"accept q=0", "GET", "/items/7", []string{"Accept", "application/vnd.v2+json;q=0, application/json"}, 200, "v1:id=7"},
+		{"no accept", "GET", "/items/7", nil, 200, "v2:id=7"},
+		{"not acceptable", "GET", "/items/7", []string{"Accept", "text/html"}, 406, ""},
+		{"query", "GET", "/media?alt=media", nil, 200, "media"},
+		{"header regexp", "GET", "/media", []string{"X-Meta", "12"}, 200, "meta"},
+		{"fallback", "GET", "/media?alt=json", nil, 200, "default"},
+		{"header", "GET", "/beta", []string{"X-Beta", "on"}, 200, "beta"},
+		{"not found", "GET", "/beta", nil, 404, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.path, tt.header...)
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == 200 {
+				assert.Equal(t, tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouterDuplicateRoute(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET("/users/:id", writeString("first")),
+		apirouter.GET("/users/:uid", writeString("last")),
+		apirouter.GET("/about", writeString("first")),
+		apirouter.GET("/about", writeString("last")),
+	)
+	assert.Equal(t, "last:uid=1", serve(r, "GET", "/users/1").Body.String())
+	assert.Equal(t, "last", serve(r, "GET", "/about").Body.String())
+}
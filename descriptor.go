@@ -0,0 +1,298 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// HTTPRule is a google.api.http binding of the RPC method, see github.com/googleapis/googleapis/google/api/http.proto.
+type HTTPRule struct {
+	Method       string // HTTP method, such as "GET", or the kind of the custom pattern
+	Pattern      string // gRPC style's path template, such as "/v1/{name=messages/*}"
+	Body         string // request field mapped to the body, "*" for all the fields not bound by the path
+	ResponseBody string // response field mapped to the body, empty for the whole response
+}
+
+// RPCMethod is an RPC method declared in the FileDescriptorSet.
+type RPCMethod struct {
+	Service         string     // fully-qualified service name, such as "library.v1.LibraryService"
+	Name            string     // method name, such as "GetBook"
+	InputType       string     // fully-qualified request message name
+	OutputType      string     // fully-qualified response message name
+	ClientStreaming bool       // whether the client streams multiple requests
+	ServerStreaming bool       // whether the server streams multiple responses
+	Rules           []HTTPRule // HTTP bindings, the primary first and then the additional bindings
+}
+
+// FullMethod returns the full method name, such as "/library.v1.LibraryService/GetBook".
+func (m *RPCMethod) FullMethod() string {
+	return "/" + m.Service + "/" + m.Name
+}
+
+// LoadFileDescriptorSet reads the RPC methods from the binary FileDescriptorSet file,
+// which is produced by "protoc --include_imports --descriptor_set_out=FILE".
+func LoadFileDescriptorSet(filename string) ([]RPCMethod, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFileDescriptorSet(data)
+}
+
+// ParseFileDescriptorSet parses the RPC methods and their google.api.http rules
+// from the binary FileDescriptorSet.
+//
+// The wire format is decoded directly, so the protobuf runtime is not required.
+// The custom pattern whose kind is not an HTTP method routed by Router is an error.
+func ParseFileDescriptorSet(data []byte) (methods []RPCMethod, err error) {
+	err = walkFields(data, func(num int, v wireValue) error {
+		if num != 1 { // FileDescriptorProto file = 1
+			return nil
+		}
+		return parseFile(v.bytes, &methods)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid file descriptor set - %v", err)
+	}
+	return methods, nil
+}
+
+// parseFile parses the FileDescriptorProto.
+func parseFile(data []byte, methods *[]RPCMethod) error {
+	var pkg string
+	var services [][]byte
+	err := walkFields(data, func(num int, v wireValue) error {
+		switch num {
+		case 2: // string package = 2
+			pkg = string(v.bytes)
+		case 6: // ServiceDescriptorProto service = 6
+			services = append(services, v.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range services {
+		if err = parseService(s, pkg, methods); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseService parses the ServiceDescriptorProto.
+func parseService(data []byte, pkg string, methods *[]RPCMethod) error {
+	var name string
+	var ms [][]byte
+	err := walkFields(data, func(num int, v wireValue) error {
+		switch num {
+		case 1: // string name = 1
+			name = string(v.bytes)
+		case 2: // MethodDescriptorProto method = 2
+			ms = append(ms, v.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if pkg != "" {
+		name = pkg + "." + name
+	}
+
+	for _, md := range ms {
+		m := RPCMethod{Service: name}
+		err = walkFields(md, func(num int, v wireValue) error {
+			switch num {
+			case 1: // string name = 1
+				m.Name = string(v.bytes)
+			case 2: // string input_type = 2
+				m.InputType = strings.TrimPrefix(string(v.bytes), ".")
+			case 3: // string output_type = 3
+				m.OutputType = strings.TrimPrefix(string(v.bytes), ".")
+			case 4: // MethodOptions options = 4
+				return walkFields(v.bytes, func(num int, v wireValue) error {
+					if num != 72295728 { // HttpRule google.api.http = 72295728
+						return nil
+					}
+					return parseHTTPRule(v.bytes, &m.Rules, true)
+				})
+			case 5: // bool client_streaming = 5
+				m.ClientStreaming = v.varint != 0
+			case 6: // bool server_streaming = 6
+				m.ServerStreaming = v.varint != 0
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		*methods = append(*methods, m)
+	}
+	return nil
+}
+
+// parseHTTPRule parses the HttpRule, the additional bindings are appended after the rule.
+func parseHTTPRule(data []byte, rules *[]HTTPRule, top bool) error {
+	var rule HTTPRule
+	var additional [][]byte
+	custom := false
+	err := walkFields(data, func(num int, v wireValue) error {
+		switch num {
+		case 2, 3, 4, 5, 6: // string get = 2, put = 3, post = 4, delete = 5, patch = 6
+			rule.Method = [...]string{"GET", "PUT", "POST", "DELETE", "PATCH"}[num-2]
+			rule.Pattern = string(v.bytes)
+		case 8: // CustomHttpPattern custom = 8
+			custom = true
+			return walkFields(v.bytes, func(num int, v wireValue) error {
+				switch num {
+				case 1: // string kind = 1
+					rule.Method = string(v.bytes)
+				case 2: // string path = 2
+					rule.Pattern = string(v.bytes)
+				}
+				return nil
+			})
+		case 7: // string body = 7
+			rule.Body = string(v.bytes)
+		case 12: // string response_body = 12
+			rule.ResponseBody = string(v.bytes)
+		case 11: // HttpRule additional_bindings = 11
+			if top { // nested additional bindings are not allowed
+				additional = append(additional, v.bytes)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if custom && new(Router).selectTree(rule.Method) == nil {
+		return fmt.Errorf("unsupported http method %q of custom pattern %q", rule.Method, rule.Pattern)
+	}
+	if rule.Pattern != "" {
+		*rules = append(*rules, rule)
+	}
+	for _, ab := range additional {
+		if err = parseHTTPRule(ab, rules, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GRPCRoutes creates the option to register the HTTP rules of the RPC methods,
+// bind returns the handler of the method and rule, or nil to skip the rule.
+// The options are applied to all of the routes.
+//
+// The patterns are parsed in gRPC style regardless of the pattern style of router,
+// including the multi-segment bindings such as "/v1/{name=messages/*}",
+// see NewGRPCPattern for the supported syntax. It panics if a pattern is invalid,
+// like the other route options.
+// Example:
+//
+// 	methods, err := apirouter.LoadFileDescriptorSet("library.pb")
+// 	...
+// 	r := apirouter.New(apirouter.GRPCRoutes(methods,
+// 		func(m *apirouter.RPCMethod, rule *apirouter.HTTPRule) apirouter.Handler {
+// 			return handlers[m.FullMethod()]
+// 		}))
+func GRPCRoutes(methods []RPCMethod, bind func(m *RPCMethod, rule *HTTPRule) Handler, options ...RouteOption) Option {
+	if bind == nil {
+		panic("router: nil bind function")
+	}
+
+	return optionFunc(func(r *Router) {
+		for i := range methods {
+			m := &methods[i]
+			for j := range m.Rules {
+				rule := &m.Rules[j]
+				if h := bind(m, rule); h != nil {
+					r.addRoute(rule.Method, rule.Pattern, NewGRPCPattern, h, options)
+				}
+			}
+		}
+	})
+}
+
+// wireValue is a field value of the protobuf wire format.
+type wireValue struct {
+	varint uint64 // value of the VARINT, I64 and I32 fields
+	bytes  []byte // value of the LEN fields
+}
+
+var errTruncated = errors.New("unexpected end of data")
+
+// walkFields decodes the fields of the protobuf message in the wire format.
+func walkFields(data []byte, fn func(num int, v wireValue) error) error {
+	for len(data) > 0 {
+		key, n := decodeVarint(data)
+		if n == 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		num, typ := int(key>>3), int(key&7)
+		if num <= 0 {
+			return fmt.Errorf("invalid field number %d", num)
+		}
+
+		var v wireValue
+		switch typ {
+		case 0: // VARINT
+			if v.varint, n = decodeVarint(data); n == 0 {
+				return errTruncated
+			}
+		case 1: // I64
+			if n = 8; len(data) < n {
+				return errTruncated
+			}
+			for i := 7; i >= 0; i-- {
+				v.varint = v.varint<<8 | uint64(data[i])
+			}
+		case 2: // LEN
+			length, m := decodeVarint(data)
+			if m == 0 || uint64(len(data)-m) < length {
+				return errTruncated
+			}
+			n = m + int(length)
+			v.bytes = data[m:n]
+		case 5: // I32
+			if n = 4; len(data) < n {
+				return errTruncated
+			}
+			for i := 3; i >= 0; i-- {
+				v.varint = v.varint<<8 | uint64(data[i])
+			}
+		default: // the groups are deprecated
+			return fmt.Errorf("unsupported wire type %d", typ)
+		}
+		data = data[n:]
+
+		if err := fn(num, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeVarint returns the value and the number of bytes read, 0 if the varint is invalid.
+func decodeVarint(data []byte) (v uint64, n int) {
+	for shift := uint(0); shift < 64 && n < len(data); shift += 7 {
+		b := data[n]
+		n++
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v, n
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+// pbField encodes the LEN field of the protobuf wire format.
+func pbField(num int, data ...[]byte) []byte {
+	var b []byte
+	for _, d := range data {
+		b = append(b, d...)
+	}
+	return append(append(pbVarint(uint64(num)<<3|2), pbVarint(uint64(len(b)))...), b...)
+}
+
+func pbString(num int, s string) []byte {
+	return pbField(num, []byte(s))
+}
+
+func pbBool(num int) []byte {
+	return append(pbVarint(uint64(num)<<3), 1)
+}
+
+func pbVarint(v uint64) (b []byte) {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func libraryDescriptorSet() []byte {
+	const httpExt = 72295728
+	method := func(name, in, out string, fields ...[]byte) []byte {
+		return pbField(2, append([][]byte{
+			pbString(1, name), pbString(2, ".library.v1."+in), pbString(3, ".library.v1."+out),
+		}, fields...)...)
+	}
+	service := pbField(6,
+		pbString(1, "LibraryService"),
+		method("GetBook", "GetBookRequest", "Book",
+			pbField(4, pbField(httpExt,
+				pbString(2, "/v1/shelves/{shelf}/books/{book}"),
+				pbField(11, pbString(2, "/v1/books/{book}"), pbField(11, pbString(2, "/nested"))),
+			))),
+		method("CreateBook", "CreateBookRequest", "Book",
+			pbField(4, pbField(httpExt,
+				pbString(4, "/v1/shelves/{shelf}/books"), pbString(7, "book"),
+			))),
+		method("MoveBook", "MoveBookRequest", "Book",
+			pbField(4, pbField(httpExt,
+				pbString(4, "/v1/books/{book}:move"), pbString(7, "*"), pbString(12, "book"),
+			))),
+		method("Inspect", "InspectRequest", "Book",
+			pbField(4, pbField(httpExt,
+				pbField(8, pbString(1, "OPTIONS"), pbString(2, "/v1/books")),
+			))),
+		method("WatchBooks", "WatchBooksRequest", "Book", pbBool(6)),
+	)
+	return pbField(1,
+		pbString(1, "library.proto"), pbString(2, "library.v1"),
+		append(pbVarint(10<<3|5), 1, 2, 3, 4), // unknown I32 field
+		service)
+}
+
+func TestParseFileDescriptorSet(t *testing.T) {
+	methods, err := apirouter.ParseFileDescriptorSet(libraryDescriptorSet())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(methods))
+
+	assert.Equal(t, "/library.v1.LibraryService/GetBook", methods[0].FullMethod())
+	assert.Equal(t, "library.v1.GetBookRequest", methods[0].InputType)
+	assert.Equal(t, "library.v1.Book", methods[0].OutputType)
+	assert.Equal(t, []apirouter.HTTPRule{
+		{Method: "GET", Pattern: "/v1/shelves/{shelf}/books/{book}"},
+		{Method: "GET", Pattern: "/v1/books/{book}"},
+	}, methods[0].Rules)
+	assert.Equal(t, []apirouter.HTTPRule{
+		{Method: "POST", Pattern: "/v1/shelves/{shelf}/books", Body: "book"},
+	}, methods[1].Rules)
+	assert.Equal(t, []apirouter.HTTPRule{
+		{Method: "POST", Pattern: "/v1/books/{book}:move", Body: "*", ResponseBody: "book"},
+	}, methods[2].Rules)
+	assert.Equal(t, []apirouter.HTTPRule{
+		{Method: "OPTIONS", Pattern: "/v1/books"},
+	}, methods[3].Rules)
+	assert.True(t, methods[4].ServerStreaming)
+	assert.False(t, methods[4].ClientStreaming)
+	assert.Empty(t, methods[4].Rules)
+
+	_, err = apirouter.ParseFileDescriptorSet([]byte{0x0a, 0x05, 0x01})
+	assert.Error(t, err)
+	_, err = apirouter.ParseFileDescriptorSet([]byte{0x0b})
+	assert.Error(t, err)
+
+	// the custom kind must be an HTTP method routed by Router
+	custom := func(kind string) []byte {
+		return pbField(1, pbString(2, "library.v1"), pbField(6, pbString(1, "LibraryService"),
+			pbField(2, pbString(1, "ListBooks"), pbField(4, pbField(72295728,
+				pbField(8, pbString(1, kind), pbString(2, "/v1/books")))))))
+	}
+	methods, err = apirouter.ParseFileDescriptorSet(custom("HEAD"))
+	if assert.NoError(t, err) && assert.Equal(t, 1, len(methods)) {
+		assert.Equal(t, []apirouter.HTTPRule{{Method: "HEAD", Pattern: "/v1/books"}}, methods[0].Rules)
+	}
+	_, err = apirouter.ParseFileDescriptorSet(custom("LIST"))
+	assert.EqualError(t, err, `invalid file descriptor set - unsupported http method "LIST" of custom pattern "/v1/books"`)
+	_, err = apirouter.ParseFileDescriptorSet(custom("*"))
+	assert.Error(t, err)
+}
+
+func TestGRPCRoutes(t *testing.T) {
+	methods, _ := apirouter.ParseFileDescriptorSet(libraryDescriptorSet())
+	r := apirouter.New(apirouter.GRPCRoutes(methods,
+		func(m *apirouter.RPCMethod, rule *apirouter.HTTPRule) apirouter.Handler {
+			if m.Name == "CreateBook" {
+				return nil
+			}
+			return writeString(m.Name)
+		}))
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/v1/shelves/1/books/2", 200, "GetBook:shelf=1:book=2"},
+		{"GET", "/v1/books/2", 200, "GetBook:book=2"},
+		{"POST", "/v1/books/2:move", 200, "MoveBook:book=2"},
+		{"OPTIONS", "/v1/books", 200, "Inspect"},
+		{"POST", "/v1/shelves/1/books", 404, ""},
+		{"GET", "/nested", 404, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.path)
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		if tt.status == 200 {
+			assert.Equal(t, tt.body, w.Body.String(), tt.path)
+		}
+	}
+}
+
+func TestGRPCRoutesMultiSegment(t *testing.T) {
+	const httpExt = 72295728
+	get := func(name, pattern string) []byte {
+		return pbField(2, pbString(1, name), pbString(2, ".messages.v1.Request"), pbString(3, ".messages.v1.Response"),
+			pbField(4, pbField(httpExt, pbString(2, pattern))))
+	}
+	methods, err := apirouter.ParseFileDescriptorSet(pbField(1,
+		pbString(1, "messages.proto"), pbString(2, "messages.v1"),
+		pbField(6, pbString(1, "MessageService"),
+			get("GetMessage", "/v1/{name=messages/*}"),
+			get("GetBook", "/v1/{name=shelves/*/books/*}:get"),
+			get("GetProfile", "/v1/{name=users/*/profile}"),
+			get("GetFile", "/v1/{name=files/**}"),
+		)))
+	assert.NoError(t, err)
+
+	r := apirouter.New(apirouter.GRPCRoutes(methods,
+		func(m *apirouter.RPCMethod, rule *apirouter.HTTPRule) apirouter.Handler {
+			return writeString(m.Name)
+		}))
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/v1/messages/1", 200, "GetMessage:name=messages/1"},
+		{"/v1/shelves/1/books/2:get", 200, "GetBook:name=shelves/1/books/2:=2"},
+		{"/v1/users/7/profile", 200, "GetProfile:name=users/7/profile"},
+		{"/v1/files/a/b", 200, "GetFile:name=files/a/b"},
+		{"/v1/messages", 404, ""},
+		{"/v1/shelves/1/books/2", 404, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, "GET", tt.path)
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		if tt.status == 200 {
+			assert.Equal(t, tt.body, w.Body.String(), tt.path)
+		}
+	}
+
+	for _, pattern := range []string{"/v1/{name=a/**/b}", "/v1/{name=a/b}", "/v1/{name=a//*}", "/v1/{name=a/{b}}", "/v1/{name=a/*"} {
+		_, err := apirouter.NewGRPCPattern(pattern, nil)
+		assert.Error(t, err, pattern)
+	}
+}
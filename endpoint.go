@@ -0,0 +1,202 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// EndpointOption configures the handler created by Endpoint.
+type EndpointOption interface {
+	applyEndpoint(*endpoint)
+}
+
+type endpointOptionFunc func(*endpoint)
+
+func (f endpointOptionFunc) applyEndpoint(e *endpoint) {
+	f(e)
+}
+
+// EndpointCodecs creates the endpoint option to set the codecs of the request and response bodies,
+// the first one is preferred. The default is DefaultCodecs.
+func EndpointCodecs(codecs ...Codec) EndpointOption {
+	if len(codecs) == 0 {
+		panic("router: no codecs")
+	}
+	cs := NewCodecs(codecs...)
+	return endpointOptionFunc(func(e *endpoint) {
+		e.codecs = cs
+	})
+}
+
+// EndpointRegistry creates the endpoint option to set the registry of the codecs,
+// which may be shared by several endpoints. The default is DefaultCodecs.
+func EndpointRegistry(cs *Codecs) EndpointOption {
+	if cs == nil {
+		panic("router: nil codecs")
+	}
+	return endpointOptionFunc(func(e *endpoint) {
+		e.codecs = cs
+	})
+}
+
+// EndpointErrorHandler creates the endpoint option to set the handler which replies
+// the errors, including the errors returned by the function, such as WriteProblem.
+// The default is the error handler of the router, see ErrorHandler.
+func EndpointErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) EndpointOption {
+	if handler == nil {
+		panic("router: nil handler")
+	}
+	return endpointOptionFunc(func(e *endpoint) {
+		e.errorHandler = handler
+	})
+}
+
+type endpoint struct {
+	codecs       *Codecs
+	errorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Endpoint returns the handler which calls the typed function fn.
+//
+// The request is built from the body, decoded by the codec of Content-Type,
+// then the struct fields tagged with `query:"name"` and `path:"name"` are set
+// from the query and path parameters, such as:
+//
+// 	type GetUserRequest struct {
+// 		ID     int      `path:"id"`
+// 		Fields []string `query:"fields"`
+// 	}
+//
+// The response is encoded by the codec negotiated by the Accept header, see Codecs.
+// It is replied with 200 (OK), or the status returned by its method StatusCode() int if it has,
+// or 204 (No Content) if it is nil or struct{}.
+//
+// The errors are replied by the error handler of the router, which also records them
+// in HandleInfo.Err, or by EndpointErrorHandler: 400 (Bad Request) for the invalid parameters and body,
+// 413 (Request Entity Too Large) if the body is larger than DefaultMaxBodyBytes,
+// 415 (Unsupported Media Type) and 406 (Not Acceptable) if no codec is available,
+// and the errors returned by fn, see HTTPError.
+func Endpoint[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), options ...EndpointOption) Handler {
+	if fn == nil {
+		panic("router: nil function")
+	}
+
+	e := &endpoint{
+		codecs:       DefaultCodecs,
+		errorHandler: replyError,
+	}
+	for _, opt := range options {
+		opt.applyEndpoint(e)
+	}
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+
+	return func(w http.ResponseWriter, r *http.Request, ps Params) {
+		out, n := e.codecs.negotiate(r)
+		if out == nil {
+			e.errorHandler(w, r, errNotAcceptable)
+			return
+		}
+
+		var req Req
+		if err := e.bind(r, ps, reflect.ValueOf(&req).Elem(), reqType); err != nil {
+			e.errorHandler(w, r, err)
+			return
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			e.errorHandler(w, r, err)
+			return
+		}
+		e.write(w, r, out, n > 1, resp)
+	}
+}
+
+// bind builds the request v from the body, query and path, in that order.
+func (e *endpoint) bind(r *http.Request, ps Params, v reflect.Value, t reflect.Type) error {
+	if t.Kind() == reflect.Ptr {
+		v.Set(reflect.New(t.Elem()))
+		v, t = v.Elem(), t.Elem()
+	}
+
+	if err := e.codecs.ReadRequest(r, v.Addr().Interface()); err != nil {
+		return err
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	query := r.URL.Query()
+	for _, f := range endpointFields(t) {
+		if f.query != "" {
+			if values := query[f.query]; len(values) > 0 {
+				if err := setProtoValues(v.Field(f.index), values); err != nil {
+					return invalidRequest("invalid query parameter "+f.query, err)
+				}
+			}
+		}
+		if f.path != "" {
+			for i := 0; i < ps.Count(); i++ {
+				if ps.Name(i) != f.path {
+					continue
+				}
+				if err := setProtoScalar(v.Field(f.index), ps.Value(i)); err != nil {
+					return invalidRequest("invalid path parameter "+f.path, err)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// write replies the response encoded by the codec.
+func (e *endpoint) write(w http.ResponseWriter, r *http.Request, c Codec, vary bool, resp interface{}) {
+	v := reflect.ValueOf(resp)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) || v.Type() == emptyStructType {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	status := http.StatusOK
+	if sc, ok := resp.(interface{ StatusCode() int }); ok {
+		status = sc.StatusCode()
+	}
+	if err := writeCodec(w, c, vary, status, resp); err != nil {
+		e.errorHandler(w, r, err)
+	}
+}
+
+var emptyStructType = reflect.TypeOf(struct{}{})
+
+// endpointField is a struct field bound to the query or path parameter.
+type endpointField struct {
+	index int
+	query string
+	path  string
+}
+
+var endpointFieldsCache sync.Map // map[reflect.Type][]endpointField
+
+func endpointFields(t reflect.Type) []endpointField {
+	if fs, ok := endpointFieldsCache.Load(t); ok {
+		return fs.([]endpointField)
+	}
+
+	var fs []endpointField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		f := endpointField{index: i, query: sf.Tag.Get("query"), path: sf.Tag.Get("path")}
+		if sf.PkgPath == "" && (f.query != "" || f.path != "") {
+			fs = append(fs, f)
+		}
+	}
+	endpointFieldsCache.Store(t, fs)
+	return fs
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+type getItemRequest struct {
+	ID     int      `path:"id"`
+	Fields []string `query:"fields"`
+	Limit  *int     `query:"limit"`
+}
+
+type item struct {
+	ID    int      `json:"id" xml:"id"`
+	Name  string   `json:"name" xml:"name"`
+	Notes []string `json:"notes,omitempty" xml:"note"`
+}
+
+type createdItem struct {
+	item
+}
+
+func (createdItem) StatusCode() int { return http.StatusCreated }
+
+// textCodec is a custom codec for test.
+type textCodec struct{}
+
+func (textCodec) MediaType() string                     { return "text/plain" }
+func (textCodec) Marshal(v interface{}) ([]byte, error) { return []byte(fmt.Sprint(v)), nil }
+func (textCodec) Unmarshal(data []byte, v interface{}) error {
+	return errors.New("not supported")
+}
+
+func TestEndpoint(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET("/items/:id", apirouter.Endpoint(func(ctx context.Context, req getItemRequest) (*item, error) {
+			if req.ID == 0 {
+				return nil, apirouter.NewHTTPError(http.StatusNotFound, "not_found", "")
+			}
+			it := &item{ID: req.ID, Name: "pen", Notes: req.Fields}
+			if req.Limit != nil {
+				it.Notes = it.Notes[:*req.Limit]
+			}
+			return it, nil
+		}, apirouter.EndpointCodecs(apirouter.JSONCodec, apirouter.XMLCodec, textCodec{}))),
+		apirouter.POST("/items", apirouter.Endpoint(func(ctx context.Context, req *item) (createdItem, error) {
+			return createdItem{*req}, nil
+		}, apirouter.EndpointCodecs(apirouter.JSONCodec, apirouter.XMLCodec))),
+		apirouter.DELETE("/items/:id", apirouter.Endpoint(func(ctx context.Context, req struct {
+			ID int `path:"id"`
+		}) (struct{}, error) {
+			return struct{}{}, nil
+		})),
+	)
+
+	w := serve(r, "GET", "/items/1?fields=a&fields=b")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"id":1,"name":"pen","notes":["a","b"]}`, w.Body.String())
+
+	w = serve(r, "GET", "/items/1?fields=a&fields=b&limit=1", "Accept", "application/xml;q=0.9, application/json;q=0.5")
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+	assert.Equal(t, `<item><id>1</id><name>pen</name><note>a</note></item>`, w.Body.String())
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	w = serve(r, "GET", "/items/1", "Accept", "text/*, application/*;q=0.1")
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "&{1 pen []}", w.Body.String())
+
+	w = serve(r, "GET", "/items/1", "Accept", "image/png")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	w = serve(r, "GET", "/items/1", "Accept", "*/*, application/json;q=0")
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+
+	w = serve(r, "GET", "/items/x")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"invalid_request"`)
+	w = serve(r, "GET", "/items/0")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/items", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	w = post("application/json; charset=utf-8", `{"id":2,"name":"book"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"id":2,"name":"book"}`, w.Body.String())
+	w = post("application/xml", `<item><id>3</id><name>cup</name></item>`)
+	assert.Equal(t, `{"id":3,"name":"cup"}`, w.Body.String())
+	w = post("text/plain", `cup`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	w = post("application/json", `{"id":`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(r, "DELETE", "/items/1")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestEndpointErrorHandler(t *testing.T) {
+	var observed error
+	fn := func(ctx context.Context, req getItemRequest) (*item, error) {
+		return nil, apirouter.NewHTTPError(http.StatusNotFound, "not_found", "")
+	}
+	r := apirouter.New(
+		apirouter.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, "custom: "+err.Error(), http.StatusTeapot)
+		}),
+		apirouter.Interceptors(apirouter.PostInterceptorEx(
+			func(w http.ResponseWriter, r *http.Request, info *apirouter.HandleInfo) {
+				observed = info.Err
+			})),
+		apirouter.GET("/items/:id", apirouter.Endpoint(fn)),
+		apirouter.GET("/problems/:id", apirouter.Endpoint(fn, apirouter.EndpointErrorHandler(apirouter.WriteProblem))),
+	)
+
+	w := serve(r, "GET", "/items/1")
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "custom: Not Found (not_found)\n", w.Body.String())
+	assert.EqualError(t, observed, "Not Found (not_found)")
+
+	w = serve(r, "GET", "/items/x")
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	if he, ok := observed.(*apirouter.HTTPError); assert.True(t, ok) {
+		assert.Equal(t, "invalid_request", he.Code)
+	}
+
+	observed = nil
+	w = serve(r, "GET", "/problems/1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Nil(t, observed)
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrHandler is a function that can be registered to a router to
+// handle HTTP requests, which returns the error instead of writing
+// the error response itself.
+//
+// The returned error is rendered by the router's error handler,
+// see APIErr and ErrorHandler.
+type ErrHandler func(w http.ResponseWriter, r *http.Request, ps Params) error
+
+// HTTPError is an error carrying the HTTP status and the problem details.
+// It is rendered as RFC 7807 "application/problem+json" by WriteProblem.
+type HTTPError struct {
+	Status  int         // HTTP status code
+	Code    string      // application-specific error code
+	Title   string      // short summary, the status text is used if empty
+	Detail  string      // human-readable explanation
+	Details interface{} // additional details, such as the invalid fields
+	Err     error       // underlying error, not exposed to client
+}
+
+// NewHTTPError returns a new HTTPError.
+func NewHTTPError(status int, code string, detail string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Detail: detail}
+}
+
+// Error implements error.Error.
+func (e *HTTPError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.title())
+	if e.Code != "" {
+		b.WriteString(" (")
+		b.WriteString(e.Code)
+		b.WriteString(")")
+	}
+	if e.Detail != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Detail)
+	}
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *HTTPError) Unwrap() error { return e.Err }
+
+func (e *HTTPError) status() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+func (e *HTTPError) title() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	if text := http.StatusText(e.status()); text != "" {
+		return text
+	}
+	return fmt.Sprintf("HTTP %d", e.status())
+}
+
+// problem is the RFC 7807 problem details object.
+type problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// WriteProblem replies to the request with the error as
+// RFC 7807 "application/problem+json".
+//
+// It is the default error handler of router. Errors other than HTTPError
+// are replied with 500 (Internal Server Error), their messages are not exposed.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		he = &HTTPError{Status: http.StatusInternalServerError}
+	}
+
+	p := problem{
+		Type:     "about:blank",
+		Title:    he.title(),
+		Status:   he.status(),
+		Detail:   he.Detail,
+		Instance: r.URL.Path,
+		Code:     he.Code,
+		Details:  he.Details,
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// ErrorHandler creates the option to set the handler which renders the
+// errors returned by ErrHandler and the errors of Transcode. The default is WriteProblem.
+func ErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	if handler == nil {
+		panic("router: nil handler")
+	}
+
+	return optionFunc(func(r *Router) {
+		r.errorHandler = handler
+	})
+}
+
+// APIErr creates the option to registers api whose handler returns error.
+// It is similar to API.
+func APIErr(method string, pattern string, handler ErrHandler, options ...RouteOption) Option {
+	if handler == nil {
+		panic("router: nil handler")
+	}
+
+	return optionFunc(func(r *Router) {
+		API(method, pattern, func(w http.ResponseWriter, req *http.Request, ps Params) {
+			if err := handler(w, req, ps); err != nil {
+				r.handleError(w, req, err)
+			}
+		}, options...).apply(r)
+	})
+}
+
+// replyError replies the error by the router serving the request, see handleError.
+func replyError(w http.ResponseWriter, req *http.Request, err error) {
+	var r *Router // unknown, such as the handler is not served by a router
+	if c, ok := req.Context().Value(ctxKey).(*routeCtx); ok {
+		r = c.router
+	}
+	r.handleError(w, req, err)
+}
+
+// handleError records the error for the interceptors and renders it.
+//
+// A nil Router renders the error by WriteProblem.
+func (r *Router) handleError(w http.ResponseWriter, req *http.Request, err error) {
+	for rw := w; ; {
+		rec, ok := rw.(*responseRecorder)
+		if !ok {
+			break
+		}
+		rec.info.Err = err
+		rw = rec.ResponseWriter
+	}
+
+	if r != nil && r.errorHandler != nil {
+		r.errorHandler(w, req, err)
+	} else {
+		WriteProblem(w, req, err)
+	}
+}
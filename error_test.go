@@ -0,0 +1,82 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIErr(t *testing.T) {
+	var observed error
+	r := apirouter.New(
+		apirouter.APIErr("GET", "/users/:id", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) error {
+			switch ps.ByName("id") {
+			case "0":
+				return &apirouter.HTTPError{
+					Status:  http.StatusNotFound,
+					Code:    "user_not_found",
+					Detail:  "user 0 not found",
+					Details: map[string]string{"id": "0"},
+				}
+			case "1":
+				return fmt.Errorf("query: %w", errors.New("connection refused"))
+			}
+			w.Write([]byte("ok"))
+			return nil
+		}, apirouter.WithInterceptors(apirouter.PostInterceptorEx(
+			func(w http.ResponseWriter, r *http.Request, info *apirouter.HandleInfo) {
+				observed = info.Err
+			}))),
+	)
+
+	w := serve(r, "GET", "/users/0")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var p map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, map[string]interface{}{
+		"type":     "about:blank",
+		"title":    "Not Found",
+		"status":   float64(404),
+		"detail":   "user 0 not found",
+		"instance": "/users/0",
+		"code":     "user_not_found",
+		"details":  map[string]interface{}{"id": "0"},
+	}, p)
+	assert.EqualError(t, observed, "Not Found (user_not_found): user 0 not found")
+
+	w = serve(r, "GET", "/users/1")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+	assert.EqualError(t, observed, "query: connection refused")
+
+	w = serve(r, "GET", "/users/2")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.NoError(t, observed)
+}
+
+func TestErrorHandler(t *testing.T) {
+	r := apirouter.New(
+		apirouter.APIErr("GET", "/", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) error {
+			return apirouter.NewHTTPError(http.StatusTeapot, "teapot", "")
+		}),
+		apirouter.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			var he *apirouter.HTTPError
+			if errors.As(err, &he) {
+				http.Error(w, he.Code, he.Status)
+			}
+		}),
+	)
+	w := serve(r, "GET", "/")
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "teapot\n", w.Body.String())
+}
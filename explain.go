@@ -0,0 +1,215 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceStep is a step of matching the path in the trie, see Router.Explain.
+type TraceStep struct {
+	Segment string // the segment of path, or the rest of path for "static" and "wildcard"
+	// Branch is the branch tried: "host", "static", "literal", "param", "regexp", "wildcard", "verb", "end",
+	// or "candidate" for the routes with the same pattern, which are decided by the request in order.
+	Branch string
+	OK     bool   // whether the branch is taken
+	Detail string // such as the regular expression, or why the branch is not taken
+}
+
+func (s TraceStep) String() string {
+	result := "ok"
+	if !s.OK {
+		result = "failed"
+	}
+	line := fmt.Sprintf("%-8s %q %s", s.Branch, s.Segment, result)
+	if s.Detail != "" {
+		line += ", " + s.Detail
+	}
+	return line
+}
+
+// Explanation is the trace of matching a path, see Router.Explain.
+type Explanation struct {
+	Method string
+	Host   string // the request host, only set by ExplainHandler and TraceNotFound
+	Path   string
+	Steps  []TraceStep
+	Route  *RouteInfo // the matched route, nil if not found
+	Params Params     // the path parameters of the matched route
+}
+
+// String returns the trace in lines.
+func (e Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s%s\n", e.Method, e.Host, e.Path)
+	for _, s := range e.Steps {
+		fmt.Fprintf(&b, "\t%s\n", s)
+	}
+	if e.Route == nil {
+		b.WriteString("not found\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "matched %s", e.Route.Pattern.pattern)
+	for i := 0; i < e.Params.Count(); i++ {
+		fmt.Fprintf(&b, " %s=%q", e.Params.Name(i), e.Params.Value(i))
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// Explain returns the trace of matching the path in the trie of method,
+// such as which branch is tried for each segment, the regular expressions failed to match,
+// and whether the wildcard fallback is used.
+//
+// The routes with the same pattern and different versions or constraints are listed
+// as the "candidate" steps in dispatch order, the Route is the first one.
+// The routes with host of ServeMux style are not matched, they are explained by ExplainHandler
+// with the query parameter "host" and by TraceNotFound.
+//
+// It is for debugging, the matching of requests is not affected,
+// see also ExplainHandler and TraceNotFound.
+func (r *Router) Explain(method, path string) (e Explanation) {
+	return r.explain(method, "", path)
+}
+
+// explain returns the trace of matching the path, the routes with the host are tried first.
+func (r *Router) explain(method, host, path string) (e Explanation) {
+	e.Method, e.Host, e.Path = method, host, path
+	t := r.selectTree(method)
+	if t == nil {
+		e.trace(TraceStep{Branch: "end", Detail: "unknown method"})
+		return
+	}
+	if r.versioning.stripPrefix {
+		_, path = splitVersionPrefix(path)
+	}
+	rt := t.explainHost(host, path, &e)
+	if rt == nil {
+		rt = t.explain(path, &e)
+	}
+	if rt != nil {
+		e.Route = rt.info
+		for i := range rt.candidates {
+			c := &rt.candidates[i]
+			e.trace(TraceStep{Branch: "candidate", OK: true, Detail: c.describe()})
+		}
+		if len(rt.candidates) > 0 {
+			c := &rt.candidates[0] // the preferred one, the others depend on the request
+			e.Route = c.info
+			e.Params.names = c.p.fields
+			c.p.bindSpans(&e.Params)
+		}
+	}
+	return
+}
+
+// describe returns the version and constraints of the merged route's candidate.
+func (rt *route) describe() string {
+	var parts []string
+	if rt.version != nil {
+		parts = append(parts, "version "+rt.version.String())
+	}
+	for _, c := range rt.constraints {
+		parts = append(parts, c.String())
+	}
+	if len(parts) == 0 {
+		return "no condition"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ExplainHandler returns the handler which replies the explanation of
+// the query parameters "method" (default GET), "host" (optional) and "path" in plain text, such as:
+//
+// 	debug := http.NewServeMux()
+// 	debug.Handle("/debug/apirouter", r.ExplainHandler())
+//
+// It should only be served to the trusted clients.
+func (r *Router) ExplainHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		method := strings.ToUpper(query.Get("method"))
+		if method == "" {
+			method = http.MethodGet
+		}
+		path := query.Get("path")
+		if path == "" {
+			http.Error(w, "missing query parameter path", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(r.explain(method, query.Get("host"), path).String()))
+	})
+}
+
+// TraceNotFound creates the option to call the function with the explanation
+// of the request replied by the "not found" handler, such as logging why it is not found.
+//
+// The requests matched some routes are not affected.
+func TraceNotFound(trace func(r *http.Request, e Explanation)) Option {
+	if trace == nil {
+		panic("router: nil trace function")
+	}
+	return optionFunc(func(r *Router) {
+		r.traceNotFound = trace
+	})
+}
+
+// traceNotFoundHandler wraps the "not found" handler with the trace function.
+func (r *Router) traceNotFoundHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.traceNotFound(req, r.explain(req.Method, requestHost(req), req.URL.Path))
+		h.ServeHTTP(w, req)
+	})
+}
+
+// explain is the match with the trace.
+func (t *tree) explain(path string, e *Explanation) *route {
+	if rt := t.staticMatch(path); rt != nil {
+		e.trace(TraceStep{Segment: path, Branch: "static", OK: true})
+		return rt
+	}
+	e.trace(TraceStep{Segment: path, Branch: "static", Detail: "no static pattern"})
+	if len(t.base) == 0 {
+		return nil
+	}
+	return t.traceMatch(path, &e.Params, e)
+}
+
+// explainHost is the matchHost with the trace.
+func (t *tree) explainHost(host, path string, e *Explanation) *route {
+	ht := t.hosts[strings.ToLower(host)]
+	if ht == nil {
+		return nil
+	}
+	e.trace(TraceStep{Segment: host, Branch: "host", OK: true})
+	if rt := ht.explain(path, e); rt != nil {
+		return rt
+	}
+	e.Params = Params{}
+	e.trace(TraceStep{Segment: host, Branch: "host", Detail: "fall back to the patterns without host"})
+	return nil
+}
+
+// trace records the step of matching.
+func (e *Explanation) trace(step TraceStep) {
+	e.Steps = append(e.Steps, step)
+}
+
+// traceLiteral records the literal branch of the segment begins at path[begin] failed at path[i].
+func (e *Explanation) traceLiteral(path string, begin, i int, sep byte) {
+	e.trace(TraceStep{Segment: path[begin:segmentEnd(path, i, sep)], Branch: "literal",
+		Detail: fmt.Sprintf("no literal continues with %q", path[begin:i+1])})
+}
+
+// segmentEnd returns the end index of the segment including path[i].
+func segmentEnd(path string, i int, sep byte) int {
+	if end := strings.IndexByte(path[i:], sep); end >= 0 {
+		return i + end
+	}
+	return len(path)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	var traced []string
+	r := apirouter.New(
+		apirouter.GET("/users/list", writeString("list")),
+		apirouter.GET(`/users/:id=^\d+$/books`, writeString("books")),
+		apirouter.GET("/files/*path", writeString("file")),
+		apirouter.GET("/files/a/b", writeString("ab")),
+		apirouter.TraceNotFound(func(r *http.Request, e apirouter.Explanation) {
+			traced = append(traced, e.String())
+		}),
+	)
+
+	e := r.Explain("GET", "/users/list")
+	assert.Equal(t, "/users/list", e.Route.Pattern.Pattern())
+	assert.Equal(t, "GET /users/list\n\tstatic   \"/users/list\" ok\nmatched /users/list\n", e.String())
+
+	e = r.Explain("GET", "/users/1/books")
+	assert.Equal(t, `GET /users/1/books
+	static   "/users/1/books" failed, no static pattern
+	literal  "users" ok
+	literal  "1" failed, no literal continues with "1"
+	param    "1" ok
+	regexp   "1" ok, ^\d+$
+	literal  "books" ok
+	end      "" ok
+matched /users/:id=^\d+$/books id="1"
+`, e.String())
+
+	e = r.Explain("GET", "/users/x/books")
+	assert.Nil(t, e.Route)
+	assert.Contains(t, e.String(), "\tregexp   \"x\" failed, ^\\d+$\n")
+	assert.Contains(t, e.String(), "\twildcard \"\" failed, no wildcard to fall back\nnot found\n")
+
+	e = r.Explain("GET", "/files/a/c")
+	assert.Equal(t, "a/c", e.Params.ByName("path"))
+	assert.Contains(t, e.String(), "\twildcard \"a/c\" ok, fall back to the last wildcard\n")
+
+	e = r.Explain("PUSH", "/")
+	assert.Nil(t, e.Route)
+
+	// the not found requests are traced
+	assert.Equal(t, http.StatusOK, serve(r, "GET", "/users/list").Code)
+	assert.Empty(t, traced)
+	assert.Equal(t, http.StatusNotFound, serve(r, "GET", "/users/x/books").Code)
+	assert.Equal(t, 1, len(traced))
+	assert.Contains(t, traced[0], "not found")
+
+	w := httptest.NewRecorder()
+	r.ExplainHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug?path=/files/a/b", nil))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "matched /files/a/b")
+	w = httptest.NewRecorder()
+	r.ExplainHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExplainServeMux(t *testing.T) {
+	r := apirouter.NewForServeMux(
+		apirouter.MuxHandleFunc("GET /a/{x}/c/d", func(w http.ResponseWriter, r *http.Request) {}),
+		apirouter.MuxHandleFunc("GET /a/b/{y}", func(w http.ResponseWriter, r *http.Request) {}),
+		apirouter.MuxHandleFunc("GET example.com/a/{x}", func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	e := r.Explain("GET", "/a/b/c")
+	assert.Equal(t, "/a/b/{y}", e.Route.Pattern.Pattern())
+	assert.Equal(t, "c", e.Params.ByName("y"))
+
+	e = r.Explain("GET", "/a/b/c/d")
+	assert.Equal(t, "/a/{x}/c/d", e.Route.Pattern.Pattern())
+	assert.Equal(t, "b", e.Params.ByName("x"))
+	assert.Contains(t, e.String(), "no pattern has more segments, backtrack")
+
+	e = r.Explain("GET", "/a/b")
+	assert.Nil(t, e.Route)
+
+	// the routes with the host are tried first
+	w := httptest.NewRecorder()
+	r.ExplainHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug?host=example.com&path=/a/b", nil))
+	assert.Equal(t, `GET example.com/a/b
+	host     "example.com" ok
+	static   "/a/b" failed, no static pattern
+	literal  "a" ok
+	literal  "b" failed, no literal continues with "b"
+	param    "b" ok
+	end      "" ok
+	candidate "" ok, host example.com
+matched /a/{x} x="b"
+`, w.Body.String())
+	w = httptest.NewRecorder()
+	r.ExplainHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug?host=example.com&path=/a/b/c", nil))
+	assert.Contains(t, w.Body.String(), "\thost     \"example.com\" failed, fall back to the patterns without host\n")
+	assert.Contains(t, w.Body.String(), "matched /a/b/{y} y=\"c\"")
+}
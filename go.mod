@@ -1,8 +1,14 @@
 module github.com/cnotch/apirouter
 
-go 1.13
+go 1.18
 
 require (
 	github.com/cnotch/queue v0.0.0-20200326024423-6e88bdbf2ad4
 	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 )
@@ -4,7 +4,10 @@
 
 package apirouter
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 // Interceptor provides a hook to intercept the execution of the HTTP request handler.
 type Interceptor interface {
@@ -20,11 +23,51 @@ type Interceptor interface {
 	PostHandle(r *http.Request)
 }
 
+// HandleInfo holds the result of the HTTP request handler execution.
+type HandleInfo struct {
+	Status    int           // response status code, 200 if the handler does not write it explicitly
+	Size      int64         // number of response body bytes written
+	Duration  time.Duration // time elapsed from PreHandle to the handler returns
+	Recovered interface{}   // value recovered from the handler panic, nil if the handler does not panic
+	Stack     []byte        // stack trace of the handler panic
+	Err       error         // error returned by the ErrHandler
+}
+
+// PostHandlerEx is an optional interface of Interceptor, which
+// receives the response and the handle information after the handler is executed.
+//
+// If an Interceptor implements PostHandlerEx,
+// PostHandleEx is called instead of PostHandle.
+type PostHandlerEx interface {
+	PostHandleEx(w http.ResponseWriter, r *http.Request, info *HandleInfo)
+}
+
+// PostInterceptorEx provides a hook function to intercept after the HTTP request handler is executed,
+// with the response and the handle information.
+type PostInterceptorEx func(w http.ResponseWriter, r *http.Request, info *HandleInfo)
+
+// PreHandle implements Intercetor.PreHandle with no-op.
+// It always returns true.
+func (f PostInterceptorEx) PreHandle(w http.ResponseWriter, r *http.Request) bool {
+	return true
+}
+
+// PostHandle implements Intercetor.PostHandle with no-op.
+func (f PostInterceptorEx) PostHandle(r *http.Request) {}
+
+// PostHandleEx implements PostHandlerEx.PostHandleEx.
+func (f PostInterceptorEx) PostHandleEx(w http.ResponseWriter, r *http.Request, info *HandleInfo) {
+	f(w, r, info)
+}
+
 var (
-	_     Interceptor = PreInterceptor(nil)
-	_     Interceptor = PostInterceptor(nil)
-	_     Interceptor = &chainInterceptor{}
-	nopIt Interceptor = nopInterceptor{}
+	_     Interceptor   = PostInterceptorEx(nil)
+	_     PostHandlerEx = PostInterceptorEx(nil)
+	_     PostHandlerEx = &chainInterceptor{}
+	_     Interceptor   = PreInterceptor(nil)
+	_     Interceptor   = PostInterceptor(nil)
+	_     Interceptor   = &chainInterceptor{}
+	nopIt Interceptor   = nopInterceptor{}
 )
 
 // PreInterceptor provides a hook function to intercept before the HTTP request handler is executed.
@@ -100,7 +143,7 @@ func ChainInterceptor(its ...Interceptor) Interceptor {
 		return its[0]
 	}
 
-	ci := &chainInterceptor{make([]Interceptor, 0, len(its))}
+	ci := &chainInterceptor{its: make([]Interceptor, 0, len(its))}
 	for _, it := range its {
 		ci.addInterceptor(it)
 	}
@@ -116,6 +159,7 @@ func ChainInterceptor(its ...Interceptor) Interceptor {
 
 type chainInterceptor struct {
 	its []Interceptor
+	ex  bool // some interceptors implement PostHandlerEx
 }
 
 func (ci *chainInterceptor) addInterceptor(it Interceptor) {
@@ -125,8 +169,11 @@ func (ci *chainInterceptor) addInterceptor(it Interceptor) {
 
 	if subci, ok := it.(*chainInterceptor); ok {
 		ci.its = append(ci.its, subci.its...)
+		ci.ex = ci.ex || subci.ex
 	} else {
 		ci.its = append(ci.its, it)
+		_, isEx := it.(PostHandlerEx)
+		ci.ex = ci.ex || isEx
 	}
 }
 
@@ -146,7 +193,34 @@ func (ci *chainInterceptor) PostHandle(r *http.Request) {
 	}
 }
 
-// Wrap wraps the hanndler with the interceptors and transforms it into a different handler
+func (ci *chainInterceptor) PostHandleEx(w http.ResponseWriter, r *http.Request, info *HandleInfo) {
+	for i := len(ci.its) - 1; i >= 0; i-- {
+		it := ci.its[i]
+		if ex, ok := it.(PostHandlerEx); ok {
+			ex.PostHandleEx(w, r, info)
+		} else {
+			it.PostHandle(r)
+		}
+	}
+}
+
+// postHandlerEx returns the PostHandlerEx of the interceptor,
+// or nil if the interceptor does not require the handle information.
+func postHandlerEx(it Interceptor) PostHandlerEx {
+	if ci, ok := it.(*chainInterceptor); ok {
+		if ci.ex {
+			return ci
+		}
+		return nil
+	}
+	ex, _ := it.(PostHandlerEx)
+	return ex
+}
+
+// Wrap wraps the hanndler with the interceptors and transforms it into a different handler.
+//
+// The PostHandle of interceptors is executed even if the handler panics,
+// the panic is then propagated to the caller.
 func Wrap(h Handler, interceptors ...Interceptor) Handler {
 	if len(interceptors) == 0 {
 		return h
@@ -156,10 +230,20 @@ func Wrap(h Handler, interceptors ...Interceptor) Handler {
 	if it == nopIt {
 		return h
 	}
+	if ex := postHandlerEx(it); ex != nil {
+		return func(w http.ResponseWriter, r *http.Request, ps Params) {
+			rec := newResponseRecorder(w)
+			defer rec.Close()
+			if it.PreHandle(rec, r) {
+				defer rec.postHandle(ex, r)
+				h(rec, r, ps)
+			}
+		}
+	}
 	return func(w http.ResponseWriter, r *http.Request, ps Params) {
 		if it.PreHandle(w, r) {
+			defer it.PostHandle(r)
 			h(w, r, ps)
-			it.PostHandle(r)
 		}
 	}
 }
@@ -174,18 +258,29 @@ func WrapHandler(h http.Handler, interceptors ...Interceptor) http.Handler {
 	if it == nopIt {
 		return h
 	}
-	return wrapHandler{h, it}
+	return wrapHandler{h, it, postHandlerEx(it)}
 }
 
 type wrapHandler struct {
 	h  http.Handler
 	it Interceptor
+	ex PostHandlerEx
 }
 
 func (wh wrapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if wh.ex != nil {
+		rec := newResponseRecorder(w)
+		defer rec.Close()
+		if wh.it.PreHandle(rec, r) {
+			defer rec.postHandle(wh.ex, r)
+			wh.h.ServeHTTP(rec, r)
+		}
+		return
+	}
+
 	if wh.it.PreHandle(w, r) {
+		defer wh.it.PostHandle(r)
 		wh.h.ServeHTTP(w, r)
-		wh.it.PostHandle(r)
 	}
 }
 
@@ -199,10 +294,5 @@ func WrapHandlerFunc(h http.HandlerFunc, interceptors ...Interceptor) http.Handl
 	if it == nopIt {
 		return h
 	}
-	return func(w http.ResponseWriter, r *http.Request) {
-		if it.PreHandle(w, r) {
-			h(w, r)
-			it.PostHandle(r)
-		}
-	}
+	return wrapHandler{h, it, postHandlerEx(it)}.ServeHTTP
 }
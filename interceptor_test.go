@@ -5,7 +5,11 @@
 package apirouter_test
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/cnotch/apirouter"
@@ -55,3 +59,184 @@ func TestWrapAbort(t *testing.T) {
 	h(nil, nil, apirouter.Params{})
 	assert.Equal(t, "AB", signature)
 }
+
+func TestRouteInterceptors(t *testing.T) {
+	signature := ""
+	global := &traceInterceptor{"G", &signature}
+	route := &traceInterceptor{"R", &signature}
+	router := apirouter.New(
+		apirouter.GET("/users/:id", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			signature += ps.ByName("id")
+		}, apirouter.WithInterceptors(route)),
+		apirouter.GET("/about", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			signature += "0"
+		}),
+		apirouter.Interceptors(global),
+	)
+
+	serve(router, "GET", "/users/1")
+	assert.Equal(t, "GR1rg", signature)
+
+	signature = ""
+	serve(router, "GET", "/about")
+	assert.Equal(t, "G0g", signature)
+
+	routes := router.Routes()
+	assert.Len(t, routes, 2)
+	assert.Equal(t, "GET", routes[0].Method)
+	assert.Equal(t, "/users/:id", routes[0].Pattern.Pattern())
+	assert.Equal(t, []apirouter.Interceptor{global, route}, routes[0].Interceptors)
+	assert.Equal(t, []apirouter.Interceptor{global}, routes[1].Interceptors)
+}
+
+type traceInterceptor struct {
+	name      string
+	signature *string
+}
+
+func (it *traceInterceptor) PreHandle(w http.ResponseWriter, r *http.Request) bool {
+	*it.signature += it.name
+	return true
+}
+
+func (it *traceInterceptor) PostHandle(r *http.Request) {
+	*it.signature += strings.ToLower(it.name)
+}
+
+func TestGlobalInterceptorsNotFound(t *testing.T) {
+	var patterns []string
+	router := apirouter.New(
+		apirouter.Interceptors(apirouter.PreInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+			if info := apirouter.CurrentRoute(r.Context()); info != nil {
+				patterns = append(patterns, info.Method+" "+info.Pattern.Pattern()+" "+
+					apirouter.PathParams(r.Context()).ByName("id"))
+			} else {
+				patterns = append(patterns, "404 "+r.URL.Path)
+			}
+			return true
+		})),
+		apirouter.GET("/users/:id", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {}),
+		apirouter.GET("/about", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {}),
+	)
+
+	serve(router, "GET", "/users/1")
+	serve(router, "GET", "/about")
+	w := serve(router, "GET", "/none")
+	serve(router, "PICK", "/about")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, []string{"GET /users/:id 1", "GET /about ", "404 /none", "404 /about"}, patterns)
+}
+
+func TestPostHandleEx(t *testing.T) {
+	var info apirouter.HandleInfo
+	signature := ""
+	h := apirouter.Wrap(func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "hello")
+		w.(http.Flusher).Flush()
+		_, isPusher := w.(http.Pusher)
+		_, isHijacker := w.(http.Hijacker)
+		_, isReaderFrom := w.(io.ReaderFrom)
+		assert.True(t, isPusher && isHijacker && isReaderFrom)
+	},
+		apirouter.PostInterceptor(func(r *http.Request) {
+			signature += "A"
+		}),
+		apirouter.PostInterceptorEx(func(w http.ResponseWriter, r *http.Request, hi *apirouter.HandleInfo) {
+			signature += "B"
+			info = *hi
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/", nil), apirouter.Params{})
+	assert.Equal(t, "BA", signature)
+	assert.Equal(t, http.StatusCreated, info.Status)
+	assert.Equal(t, int64(5), info.Size)
+	assert.True(t, w.Flushed)
+}
+
+func TestGlobalPostHandleEx(t *testing.T) {
+	var status int
+	router := apirouter.New(
+		apirouter.Interceptors(apirouter.PostInterceptorEx(func(w http.ResponseWriter, r *http.Request, info *apirouter.HandleInfo) {
+			status = info.Status
+		})),
+		apirouter.GET("/about", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			io.WriteString(w, "about")
+		}),
+	)
+
+	serve(router, "GET", "/about")
+	assert.Equal(t, http.StatusOK, status)
+	serve(router, "GET", "/none")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestPostHandleExAllocs(t *testing.T) {
+	hello := []byte("hello")
+	h := apirouter.Wrap(func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+		w.Write(hello)
+	}, apirouter.PostInterceptorEx(func(w http.ResponseWriter, r *http.Request, hi *apirouter.HandleInfo) {}))
+
+	w := new(mockResponseWriter)
+	r := httptest.NewRequest("GET", "/", nil)
+	h(w, r, apirouter.Params{})
+	allocs := testing.AllocsPerRun(100, func() {
+		h(w, r, apirouter.Params{})
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestWrapPanic(t *testing.T) {
+	signature := ""
+	var info apirouter.HandleInfo
+	h := apirouter.Wrap(func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+		panic("oops")
+	},
+		apirouter.PostInterceptor(func(r *http.Request) {
+			signature += "A"
+		}),
+		apirouter.PostInterceptorEx(func(w http.ResponseWriter, r *http.Request, hi *apirouter.HandleInfo) {
+			signature += "B"
+			info = *hi
+		}),
+	)
+
+	assert.PanicsWithValue(t, "oops", func() {
+		h(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), apirouter.Params{})
+	})
+	assert.Equal(t, "BA", signature)
+	assert.Equal(t, "oops", info.Recovered)
+	assert.Equal(t, http.StatusInternalServerError, info.Status)
+	assert.Contains(t, string(info.Stack), "TestWrapPanic")
+}
+
+func TestRouterPanicHandler(t *testing.T) {
+	var info apirouter.HandleInfo
+	router := apirouter.New(
+		apirouter.PanicHandler(func(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+			http.Error(w, fmt.Sprint(recovered), http.StatusInternalServerError)
+		}),
+		apirouter.Interceptors(apirouter.PostInterceptorEx(func(w http.ResponseWriter, r *http.Request, hi *apirouter.HandleInfo) {
+			info = *hi
+		})),
+		apirouter.GET("/panic", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			panic("oops")
+		}),
+		apirouter.GET("/abort", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			panic(http.ErrAbortHandler)
+		}),
+	)
+
+	w := serve(router, "GET", "/panic")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "oops\n", w.Body.String())
+	assert.Equal(t, "oops", info.Recovered)
+	assert.Equal(t, http.StatusInternalServerError, info.Status)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serve(router, "GET", "/abort")
+	})
+	assert.Equal(t, http.ErrAbortHandler, info.Recovered)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Matcher maps the patterns to the values of type T, it is the engine of Router
+// decoupled from net/http, for the paths whose segments are separated by the given separator,
+// such as the MQTT topics "sensors/1/temp", the RTSP URL paths "/live/cam1"
+// or the message subjects "orders.eu.created".
+//
+// The patterns are in the default style, with the separator instead of '/':
+//
+// 	Pattern		= [ SEP ] Segment { SEP Segment }
+// 	Segment		= LITERAL | Parameter
+// 	Parameter	= ":" [ FieldPath ] [ "=" Regexp ] | "*" [ FieldPath ]
+//
+// The patterns beginning with the separator match the paths beginning with it,
+// the others match the paths without it. The wildcard must be the last segment.
+// The precedence is the same as Router, the literal segments are preferred.
+//
+// The patterns must be added before the first Match,
+// after that the Matcher is safe for concurrent use.
+type Matcher[T any] struct {
+	t      tree
+	values []T
+	once   sync.Once
+	ready  bool
+}
+
+// NewMatcher returns a new Matcher with the segment separator, such as '/' or '.'.
+func NewMatcher[T any](sep byte) *Matcher[T] {
+	if sep == 0 || sep == ':' || sep == '*' || sep == '=' {
+		panic("router: invalid separator - " + string(sep))
+	}
+	return &Matcher[T]{t: tree{sep: sep, relative: true}}
+}
+
+// Add adds the pattern mapped to the value,
+// the value of the same pattern is replaced, even if the parameter names are different.
+func (m *Matcher[T]) Add(pattern string, value T) error {
+	if m.ready {
+		panic("router: pattern added after match - " + pattern)
+	}
+
+	p, err := parseSeparated(pattern, m.t.sep, &m.t.res)
+	if err != nil {
+		return err
+	}
+	m.t.add(route{p: p, id: len(m.values)})
+	m.values = append(m.values, value)
+	return nil
+}
+
+// Match returns the value of the pattern matched the path and the captured parameters,
+// it does not allocate.
+func (m *Matcher[T]) Match(path string) (value T, ps Params, ok bool) {
+	m.once.Do(m.init)
+	if rt := m.t.match(path, &ps); rt != nil {
+		return m.values[rt.id], ps, true
+	}
+	return
+}
+
+func (m *Matcher[T]) init() {
+	m.ready = true
+	m.t.init(nil)
+}
+
+// parseSeparated parses the pattern of Matcher, whose segments are separated by sep.
+func parseSeparated(pattern string, sep byte, regexps *[]*regexp.Regexp) (p Pattern, err error) {
+	segments := pattern
+	absolute := len(segments) > 0 && segments[0] == sep
+	if absolute {
+		segments = segments[1:]
+	}
+
+	b := NewPatternBuilder(regexps)
+	b.sep = sep
+	for _, seg := range strings.Split(segments, string(sep)) {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			if i := strings.IndexByte(seg, '='); i >= 0 {
+				b.RegexpParam(seg[1:i], seg[i+1:])
+			} else {
+				b.Param(seg[1:])
+			}
+		case strings.HasPrefix(seg, "*"):
+			b.Wildcard(seg[1:])
+		default:
+			b.Literal(seg)
+		}
+	}
+
+	if p, err = b.Build(pattern); err == nil && absolute {
+		p.key = string(sep) + p.key // the empty leading segment
+	}
+	return
+}
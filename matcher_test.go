@@ -0,0 +1,84 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		sep      byte
+		patterns []string
+		path     string
+		want     string
+		params   map[string]string
+	}{
+		{"topic", '/', []string{"sensors/:id/temp", "sensors/:id/*", "sensors/1/temp"}, "sensors/2/temp", "sensors/:id/temp", map[string]string{"id": "2"}},
+		{"topic static", '/', []string{"sensors/:id/temp", "sensors/1/temp"}, "sensors/1/temp", "sensors/1/temp", nil},
+		{"topic wildcard", '/', []string{"sensors/:id/temp", "sensors/:id/*rest"}, "sensors/2/hum/avg", "sensors/:id/*rest", map[string]string{"id": "2", "rest": "hum/avg"}},
+		{"topic absolute", '/', []string{"sensors/:id"}, "/sensors/1", "", nil},
+		{"rtsp", '/', []string{"/live/:stream", "/vod/*file"}, "/live/cam1", "/live/:stream", map[string]string{"stream": "cam1"}},
+		{"rtsp root", '/', []string{"/", "/live/:stream"}, "/", "/", nil},
+		{"rtsp relative", '/', []string{"/live/:stream"}, "live/cam1", "", nil},
+		{"subject", '.', []string{"orders.:region.created", "orders.*"}, "orders.eu.created", "orders.:region.created", map[string]string{"region": "eu"}},
+		{"subject wildcard", '.', []string{"orders.:region.created", "orders.*event"}, "orders.eu.deleted", "orders.*event", map[string]string{"event": "eu.deleted"}},
+		{"subject slash", '.', []string{"files.:name"}, "files.a/b", "files.:name", map[string]string{"name": "a/b"}},
+		{"regexp", '.', []string{`devices.:id=^\d+$`, "devices.:name"}, "devices.42", `devices.:id=^\d+$`, map[string]string{"id": "42"}},
+		{"regexp fallback", '.', []string{`devices.:id=^\d+$`, "devices.:name"}, "devices.lamp", "devices.:name", map[string]string{"name": "lamp"}},
+		{"empty", '.', []string{"", "a"}, "", "", nil},
+		{"not found", '.', []string{"a.b"}, "a.c", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := apirouter.NewMatcher[string](tt.sep)
+			for _, p := range tt.patterns {
+				assert.NoError(t, m.Add(p, p))
+			}
+			v, ps, ok := m.Match(tt.path)
+			assert.Equal(t, tt.want, v)
+			assert.Equal(t, tt.want != "" || tt.name == "empty", ok)
+			assert.Equal(t, len(tt.params), ps.Count())
+			for name, value := range tt.params {
+				assert.Equal(t, value, ps.ByName(name))
+			}
+		})
+	}
+}
+
+func TestMatcherAdd(t *testing.T) {
+	m := apirouter.NewMatcher[int]('.')
+	assert.NoError(t, m.Add("a.:x", 1))
+	assert.NoError(t, m.Add("a.:y", 2))
+	assert.Error(t, m.Add("a.*.b", 3))
+	assert.Error(t, m.Add("a.:x=(", 3))
+
+	v, ps, ok := m.Match("a.b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, "b", ps.ByName("y"))
+
+	assert.Panics(t, func() { m.Add("b", 4) })
+	assert.Panics(t, func() { apirouter.NewMatcher[int](':') })
+}
+
+func TestMatcherAllocs(t *testing.T) {
+	m := apirouter.NewMatcher[int]('/')
+	m.Add("/live/:app/:stream", 1)
+	m.Add("/vod/*file", 2)
+	m.Match("/")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, ps, ok := m.Match("/live/app/cam1"); !ok || ps.ByName("stream") != "cam1" {
+			t.Fatal("not matched")
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+}
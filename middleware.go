@@ -0,0 +1,122 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Middleware is the standard middleware of the ecosystem,
+// which wraps a http.Handler and transforms it into a different http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// WithMiddleware creates the route option to wrap the route handler
+// with the standard middlewares.
+//
+// Middlewares are executed in left-to-right order, before the route's interceptors.
+// The path parameters are stored in the request's context,
+// so they are still passed to the Handler even if the middleware replaces the request.
+func WithMiddleware(mws ...Middleware) RouteOption {
+	for _, mw := range mws {
+		if mw == nil {
+			panic("router: nil middleware")
+		}
+	}
+
+	return routeOptionFunc(func(rt *route) {
+		rt.middlewares = append(rt.middlewares, mws...)
+	})
+}
+
+// Use creates the option to wrap the router with the standard middlewares,
+// which are executed for all requests, including the requests not found,
+// before the global interceptors.
+//
+// Within a Group, the middlewares only wrap the routes of the group.
+func Use(mws ...Middleware) Option {
+	for _, mw := range mws {
+		if mw == nil {
+			panic("router: nil middleware")
+		}
+	}
+
+	return optionFunc(func(r *Router) {
+		if r.scope != nil {
+			r.scope.options = append(r.scope.options, WithMiddleware(mws...))
+			return
+		}
+		r.middlewares = append(r.middlewares, mws...)
+	})
+}
+
+// scope is a group of routes sharing the pattern prefix and route options.
+type scope struct {
+	prefix  string
+	options []RouteOption
+}
+
+// Group creates the option to register a group of apis,
+// whose patterns share the given prefix.
+//
+// The Use and Interceptors options in the group only apply to the routes of the group.
+//
+// Example:
+//
+// 	r := apirouter.New(
+// 		apirouter.Group("/admin",
+// 			apirouter.Use(csrf),
+// 			apirouter.GET("/users/:id", getUser), // pattern: /admin/users/:id
+// 		),
+// 	)
+func Group(prefix string, options ...Option) Option {
+	if !strings.HasPrefix(prefix, "/") {
+		panic(fmt.Errorf("router: group prefix no leading / - %q", prefix))
+	}
+	prefix = strings.TrimRight(prefix, "/")
+
+	return optionFunc(func(r *Router) {
+		parent := r.scope
+		s := &scope{prefix: prefix}
+		if parent != nil {
+			s.prefix = parent.prefix + prefix
+			s.options = append([]RouteOption(nil), parent.options...)
+		}
+
+		r.scope = s
+		defer func() { r.scope = parent }()
+		for _, opt := range options {
+			opt.apply(r)
+		}
+	})
+}
+
+// chainMiddlewares wraps the handler with the middlewares,
+// the first middleware is the outermost.
+func chainMiddlewares(h http.Handler, mws []Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// wrapMiddlewares wraps the Handler with the middlewares.
+func wrapMiddlewares(h Handler, mws []Middleware) Handler {
+	if len(mws) == 0 {
+		return h
+	}
+
+	handler := chainMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ps Params
+		if p := PathParams(r.Context()); p != nil {
+			ps = *p
+		}
+		h(w, r, ps)
+	}), mws)
+	return func(w http.ResponseWriter, r *http.Request, ps Params) {
+		handler.ServeHTTP(w, withParams(r, ps))
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey string
+
+func tagMiddleware(tag string) apirouter.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Tag", tag)
+			// replace the request, like the ecosystem's middlewares do
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey(tag), tag)))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	r := apirouter.New(
+		apirouter.Use(tagMiddleware("router")),
+		apirouter.GET("/users/:id", writeString("user"), apirouter.WithMiddleware(tagMiddleware("route"))),
+		apirouter.Group("/admin",
+			apirouter.Use(tagMiddleware("admin")),
+			apirouter.GET("/users/:id", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+				assert.Equal(t, "admin", r.Context().Value(ctxKey("admin")))
+				writeString("admin")(w, r, ps)
+			}, apirouter.WithMiddleware(tagMiddleware("route"))),
+			apirouter.Group("/sys/",
+				apirouter.HandleFunc("GET", "/info/:name", func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("info:" + apirouter.PathParams(r.Context()).ByName("name")))
+				}),
+			),
+		),
+		apirouter.GET("/about", writeString("about")),
+	)
+
+	w := serve(r, "GET", "/users/1")
+	assert.Equal(t, "user:id=1", w.Body.String())
+	assert.Equal(t, []string{"router", "route"}, w.Header()["X-Tag"])
+
+	w = serve(r, "GET", "/admin/users/2")
+	assert.Equal(t, "admin:id=2", w.Body.String())
+	assert.Equal(t, []string{"router", "admin", "route"}, w.Header()["X-Tag"])
+
+	w = serve(r, "GET", "/admin/sys/info/x")
+	assert.Equal(t, "info:x", w.Body.String())
+	assert.Equal(t, []string{"router", "admin"}, w.Header()["X-Tag"])
+
+	w = serve(r, "GET", "/about")
+	assert.Equal(t, []string{"router"}, w.Header()["X-Tag"])
+
+	w = serve(r, "GET", "/none")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, []string{"router"}, w.Header()["X-Tag"])
+}
+
+func TestGroupInterceptors(t *testing.T) {
+	signature := ""
+	r := apirouter.New(
+		apirouter.Group("/admin",
+			apirouter.Interceptors(&traceInterceptor{"A", &signature}),
+			apirouter.GET("/users", writeString("admin")),
+		),
+		apirouter.GET("/users", writeString("user")),
+	)
+
+	serve(r, "GET", "/users")
+	assert.Equal(t, "", signature)
+	serve(r, "GET", "/admin/users")
+	assert.Equal(t, "Aa", signature)
+}
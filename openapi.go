@@ -0,0 +1,371 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// OpenAPI is the OpenAPI 3.1 document, only the commonly used fields are supported.
+type OpenAPI struct {
+	OpenAPI    string               `json:"openapi" yaml:"openapi"`
+	Info       Info                 `json:"info" yaml:"info"`
+	Paths      map[string]*PathItem `json:"paths" yaml:"paths"`
+	Components *Components          `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+// Info is the metadata about the API.
+type Info struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version" yaml:"version"`
+}
+
+// PathItem describes the operations available on a single path.
+type PathItem struct {
+	Summary     string       `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string       `json:"description,omitempty" yaml:"description,omitempty"`
+	Get         *Operation   `json:"get,omitempty" yaml:"get,omitempty"`
+	Put         *Operation   `json:"put,omitempty" yaml:"put,omitempty"`
+	Post        *Operation   `json:"post,omitempty" yaml:"post,omitempty"`
+	Delete      *Operation   `json:"delete,omitempty" yaml:"delete,omitempty"`
+	Options     *Operation   `json:"options,omitempty" yaml:"options,omitempty"`
+	Head        *Operation   `json:"head,omitempty" yaml:"head,omitempty"`
+	Patch       *Operation   `json:"patch,omitempty" yaml:"patch,omitempty"`
+	Trace       *Operation   `json:"trace,omitempty" yaml:"trace,omitempty"`
+	Parameters  []*Parameter `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+}
+
+// Parameter describes a single operation parameter,
+// which is located in "path", "query", "header" or "cookie".
+type Parameter struct {
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// RequestBody describes a single request body.
+type RequestBody struct {
+	Description string                `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty" yaml:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content" yaml:"content"`
+}
+
+// Response describes a single response from an API operation.
+type Response struct {
+	Description string                `json:"description" yaml:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// MediaType provides the schema for the media type.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// Components holds the reusable schemas.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+}
+
+// Operation returns the operation of the HTTP method, nil if not exists.
+func (pi *PathItem) Operation(method string) *Operation {
+	if op := pi.operationRef(method); op != nil {
+		return *op
+	}
+	return nil
+}
+
+func (pi *PathItem) operationRef(method string) **Operation {
+	switch method {
+	case http.MethodGet:
+		return &pi.Get
+	case http.MethodPut:
+		return &pi.Put
+	case http.MethodPost:
+		return &pi.Post
+	case http.MethodDelete:
+		return &pi.Delete
+	case http.MethodOptions:
+		return &pi.Options
+	case http.MethodHead:
+		return &pi.Head
+	case http.MethodPatch:
+		return &pi.Patch
+	case http.MethodTrace:
+		return &pi.Trace
+	}
+	return nil
+}
+
+// operationDoc is the document metadata of route.
+type operationDoc struct {
+	id          string
+	summary     string
+	description string
+	tags        []string
+	request     reflect.Type
+	responses   []responseDoc
+	hidden      bool
+}
+
+type responseDoc struct {
+	status int
+	typ    reflect.Type // nil if no content
+}
+
+// docOption creates the route option to set the document metadata of the route.
+func docOption(fn func(doc *operationDoc)) RouteOption {
+	return routeOptionFunc(func(rt *route) {
+		if rt.doc == nil {
+			rt.doc = &operationDoc{}
+		}
+		fn(rt.doc)
+	})
+}
+
+// OperationID creates the route option to set the unique id of the operation in the OpenAPI document.
+func OperationID(id string) RouteOption {
+	return docOption(func(doc *operationDoc) { doc.id = id })
+}
+
+// Summary creates the route option to set the short summary of the operation in the OpenAPI document.
+func Summary(summary string) RouteOption {
+	return docOption(func(doc *operationDoc) { doc.summary = summary })
+}
+
+// Description creates the route option to set the description of the operation in the OpenAPI document.
+func Description(description string) RouteOption {
+	return docOption(func(doc *operationDoc) { doc.description = description })
+}
+
+// Tags creates the route option to add the tags of the operation in the OpenAPI document.
+func Tags(tags ...string) RouteOption {
+	return docOption(func(doc *operationDoc) { doc.tags = append(doc.tags, tags...) })
+}
+
+// RequestType creates the route option to describe the request body
+// with the type of the given value, such as RequestType(User{}).
+// The body is in JSON unless the media types are declared by Consumes.
+func RequestType(v interface{}) RouteOption {
+	t := reflect.TypeOf(v)
+	return docOption(func(doc *operationDoc) { doc.request = t })
+}
+
+// ResponseType creates the route option to describe the response of the status
+// with the type of the given value, the nil value means the response has no content.
+// The response is in JSON unless the media types are declared by Produces.
+func ResponseType(status int, v interface{}) RouteOption {
+	t := reflect.TypeOf(v)
+	return docOption(func(doc *operationDoc) {
+		doc.responses = append(doc.responses, responseDoc{status, t})
+	})
+}
+
+// hiddenRoute excludes the route from the OpenAPI document.
+var hiddenRoute = docOption(func(doc *operationDoc) { doc.hidden = true })
+
+// OpenAPI generates the OpenAPI 3.1 document of the registered routes.
+//
+// The path parameters are typed by the regular expressions of the patterns,
+// the parameters of MatchHeader, MatchHeaderRegexp and MatchQuery are also documented.
+// If several routes share the same method and path, such as the routes with constraints,
+// only the first registered one is documented.
+func (r *Router) OpenAPI(info Info) *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+	g := newSchemaGenerator()
+
+	routes := make([]*route, 0, len(r.routes))
+	for i := range r.routes {
+		rt := &r.routes[i]
+		// skip the hidden routes, and HEAD of the GET routes
+		if (rt.doc != nil && rt.doc.hidden) || rt.fallback == 1 || rt.method == http.MethodConnect {
+			continue
+		}
+		routes = append(routes, rt)
+	}
+	// the routes with exact method take precedence
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].fallback < routes[j].fallback })
+
+	for _, rt := range routes {
+		path, params := r.openAPIPath(rt)
+		item := doc.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		ref := item.operationRef(rt.method)
+		if *ref != nil {
+			continue
+		}
+		*ref = r.openAPIOperation(rt, params, g)
+	}
+
+	if len(g.schemas) > 0 {
+		doc.Components = &Components{Schemas: g.schemas}
+	}
+	return doc
+}
+
+// openAPIPath converts the pattern of route to the OpenAPI path template.
+func (r *Router) openAPIPath(rt *route) (path string, params []*Parameter) {
+	var b strings.Builder
+	if rt.version != nil && r.versioning.stripPrefix {
+		b.WriteString("/" + rt.version.String())
+	}
+
+	field := 0
+	for _, seg := range splitKey(rt.p, r.selectTree(rt.method).res) {
+		b.WriteByte('/')
+		if seg.Kind == LiteralSegment {
+			b.WriteString(seg.Text)
+			continue
+		}
+
+		name := rt.p.fields[field]
+		field++
+		if name == "" {
+			name = "param" + strconv.Itoa(field)
+		}
+		b.WriteString("{" + name + "}")
+
+		param := &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		switch seg.Kind {
+		case RegexpSegment:
+			if seg.Regexp != nil {
+				param.Schema = regexpSchema(seg.Regexp.String())
+			}
+		case WildcardSegment:
+			param.Description = "The rest of the path, which may contain '/'."
+		}
+		params = append(params, param)
+	}
+	b.WriteString(rt.p.verb)
+	return b.String(), params
+}
+
+// regexpSchema returns the schema of the path parameter matched the regular expression.
+func regexpSchema(expr string) *Schema {
+	switch strings.TrimSuffix(strings.TrimPrefix(expr, "^"), "$") {
+	case `\d+`, `[0-9]+`, `-?\d+`, `-?[0-9]+`:
+		return &Schema{Type: "integer"}
+	}
+	return &Schema{Type: "string", Pattern: expr}
+}
+
+func (r *Router) openAPIOperation(rt *route, params []*Parameter, g *schemaGenerator) *Operation {
+	op := &Operation{
+		Parameters: params,
+		Responses:  make(map[string]*Response),
+	}
+	for _, c := range rt.constraints {
+		if c.param != nil {
+			p := *c.param
+			op.Parameters = append(op.Parameters, &p)
+		}
+	}
+	if rt.version != nil {
+		_, op.Deprecated = r.versioning.deprecated[*rt.version]
+	}
+
+	if d := rt.doc; d != nil {
+		op.OperationID = d.id
+		op.Summary = d.summary
+		op.Description = d.description
+		op.Tags = append([]string(nil), d.tags...)
+		if d.request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  contentOf(rt.consumes, g.schemaOf(d.request)),
+			}
+		}
+		for _, resp := range d.responses {
+			res := &Response{Description: http.StatusText(resp.status)}
+			if resp.typ != nil {
+				res.Content = contentOf(rt.produces, g.schemaOf(resp.typ))
+			}
+			op.Responses[strconv.Itoa(resp.status)] = res
+		}
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &Response{Description: http.StatusText(http.StatusOK)}
+	}
+	return op
+}
+
+// contentOf returns the content of the media types sharing the schema,
+// the default is "application/json".
+func contentOf(mediaTypes []string, schema *Schema) map[string]*MediaType {
+	if len(mediaTypes) == 0 {
+		return map[string]*MediaType{"application/json": {Schema: schema}}
+	}
+	content := make(map[string]*MediaType, len(mediaTypes))
+	for _, mt := range mediaTypes {
+		content[mt] = &MediaType{Schema: schema}
+	}
+	return content
+}
+
+// ServeOpenAPI creates the option to serve the OpenAPI document of the router
+// on GET the path, such as "/openapi.json".
+// The document is in YAML if the path ends with ".yaml" or ".yml", otherwise in JSON.
+//
+// The document is generated on the first request, so it includes the routes registered after it,
+// but not the route of itself.
+func ServeOpenAPI(path string, info Info) Option {
+	asYAML := strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+
+	return optionFunc(func(r *Router) {
+		var (
+			once sync.Once
+			data []byte
+			err  error
+		)
+		API(http.MethodGet, path, func(w http.ResponseWriter, req *http.Request, ps Params) {
+			once.Do(func() {
+				if asYAML {
+					data, err = yaml.Marshal(r.OpenAPI(info))
+				} else {
+					data, err = json.MarshalIndent(r.OpenAPI(info), "", "  ")
+				}
+			})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if asYAML {
+				w.Header().Set("Content-Type", "application/yaml")
+			} else {
+				w.Header().Set("Content-Type", "application/json")
+			}
+			w.Write(data)
+		}, hiddenRoute).apply(r)
+	})
+}
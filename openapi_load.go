@@ -0,0 +1,257 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// LoadOpenAPI parses the OpenAPI 3 document in JSON or YAML.
+func LoadOpenAPI(data []byte) (*OpenAPI, error) {
+	doc := &OpenAPI{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("invalid openapi document - %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version - %q", doc.OpenAPI)
+	}
+	return doc, nil
+}
+
+// LoadOpenAPIFile reads and parses the OpenAPI 3 document file in JSON or YAML.
+func LoadOpenAPIFile(filename string) (*OpenAPI, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return LoadOpenAPI(data)
+}
+
+// BindError reports the operations of OpenAPI document and the handlers that are not bound.
+type BindError struct {
+	Missing []string // operations without handler, the operationId or "METHOD path" if no operationId
+	Extra   []string // operationIds of the handlers without operation
+}
+
+func (e *BindError) Error() string {
+	var b strings.Builder
+	b.WriteString("openapi operations are not bound")
+	if len(e.Missing) > 0 {
+		b.WriteString(", missing handlers: " + strings.Join(e.Missing, ", "))
+	}
+	if len(e.Extra) > 0 {
+		b.WriteString(", extra handlers: " + strings.Join(e.Extra, ", "))
+	}
+	return b.String()
+}
+
+// openAPIMethods are the methods of PathItem in registration order.
+var openAPIMethods = []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+	http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace}
+
+// OpenAPIRoutes returns the option to register the operations of the OpenAPI document,
+// which are bound to the handlers by operationId, the options are applied to all of the routes.
+//
+// The path parameters are translated into the regular expression parameters
+// by the "pattern", "enum" or "format" (int32, int64, uuid and date) of their schemas,
+// the routes are registered regardless of the pattern style of router.
+//
+// If some operations or handlers are not bound, a *BindError is returned
+// with the option which registers the bound operations.
+// Example:
+//
+// 	doc, err := apirouter.LoadOpenAPIFile("api.yaml")
+// 	...
+// 	routes, err := apirouter.OpenAPIRoutes(doc, map[string]apirouter.Handler{
+// 		"getUser": getUser,
+// 	})
+// 	...
+// 	r := apirouter.New(routes)
+func OpenAPIRoutes(doc *OpenAPI, handlers map[string]Handler, options ...RouteOption) (Option, error) {
+	type binding struct {
+		method string
+		path   string
+		parse  PatternParser
+		h      Handler
+		op     *Operation
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var (
+		bindings []binding
+		missing  []string
+		bound    = make(map[string]bool)
+	)
+	for _, path := range paths {
+		item := doc.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op := item.Operation(method)
+			if op == nil {
+				continue
+			}
+			parse, err := openAPIPathParser(doc, path, mergeParameters(item.Parameters, op.Parameters))
+			if err != nil {
+				return nil, err
+			}
+			h := handlers[op.OperationID]
+			if op.OperationID == "" || h == nil {
+				if op.OperationID == "" {
+					missing = append(missing, method+" "+path)
+				} else {
+					missing = append(missing, op.OperationID)
+				}
+				continue
+			}
+			bound[op.OperationID] = true
+			bindings = append(bindings, binding{method, path, parse, h, op})
+		}
+	}
+
+	var extra []string
+	for id := range handlers {
+		if !bound[id] {
+			extra = append(extra, id)
+		}
+	}
+	sort.Strings(extra)
+
+	opt := optionFunc(func(r *Router) {
+		for _, b := range bindings {
+			op := b.op
+			opts := append(options[:len(options):len(options)], docOption(func(doc *operationDoc) {
+				doc.id = op.OperationID
+				doc.summary = op.Summary
+				doc.description = op.Description
+				doc.tags = append(doc.tags, op.Tags...)
+			}))
+			r.addRoute(b.method, b.path, b.parse, b.h, opts)
+		}
+	})
+	if len(missing) > 0 || len(extra) > 0 {
+		return opt, &BindError{Missing: missing, Extra: extra}
+	}
+	return opt, nil
+}
+
+// mergeParameters returns the parameters of operation,
+// which override the parameters of path item with the same name and location.
+func mergeParameters(common, own []*Parameter) []*Parameter {
+	if len(common) == 0 {
+		return own
+	}
+	params := append([]*Parameter(nil), own...)
+	for _, cp := range common {
+		overridden := false
+		for _, p := range own {
+			if p.Name == cp.Name && p.In == cp.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			params = append(params, cp)
+		}
+	}
+	return params
+}
+
+// openAPIPathParser returns the parser of the OpenAPI path template, such as "/users/{id}".
+// The prefix of the group is parsed as the literal segments.
+func openAPIPathParser(doc *OpenAPI, template string, params []*Parameter) (PatternParser, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("openapi path no leading / - %q", template)
+	}
+	exprs := make(map[string]string)
+	for _, p := range params {
+		if p.In == "path" {
+			exprs[p.Name] = paramRegexp(doc.resolve(p.Schema))
+		}
+	}
+
+	// check the template once
+	if _, err := parseOpenAPIPath(template, exprs, new([]*regexp.Regexp)); err != nil {
+		return nil, err
+	}
+	return func(pattern string, regexps *[]*regexp.Regexp) (Pattern, error) {
+		return parseOpenAPIPath(pattern, exprs, regexps)
+	}, nil
+}
+
+func parseOpenAPIPath(pattern string, exprs map[string]string, regexps *[]*regexp.Regexp) (Pattern, error) {
+	b := NewPatternBuilder(regexps)
+	path, verb := splitURLPath(pattern)
+	if verb != "" {
+		b.Verb(verb)
+	}
+	for _, seg := range strings.Split(path[1:], "/") {
+		if !strings.ContainsAny(seg, "{}") {
+			b.Literal(seg)
+			continue
+		}
+		if seg[0] != '{' || seg[len(seg)-1] != '}' || strings.Count(seg, "{") != 1 {
+			return Pattern{}, fmt.Errorf("openapi path parameter must be a full segment - %q", pattern)
+		}
+		name := seg[1 : len(seg)-1]
+		if expr := exprs[name]; expr != "" {
+			b.RegexpParam(name, expr)
+		} else {
+			b.Param(name)
+		}
+	}
+	return b.Build(pattern)
+}
+
+// formatRegexps are the regular expressions of the path parameter formats.
+var formatRegexps = map[string]string{
+	"int32": `^-?[0-9]+$`,
+	"int64": `^-?[0-9]+$`,
+	"uuid":  `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`,
+	"date":  `^[0-9]{4}-[0-9]{2}-[0-9]{2}$`,
+}
+
+// paramRegexp returns the regular expression of the path parameter schema,
+// empty if the parameter matches any segment.
+func paramRegexp(s *Schema) string {
+	switch {
+	case s == nil:
+		return ""
+	case s.Pattern != "":
+		return s.Pattern
+	case len(s.Enum) > 0:
+		alts := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			alts[i] = regexp.QuoteMeta(fmt.Sprint(v))
+		}
+		return "^(?:" + strings.Join(alts, "|") + ")$"
+	}
+	return formatRegexps[s.Format]
+}
+
+// resolve returns the schema referenced by "#/components/schemas/NAME".
+func (doc *OpenAPI) resolve(s *Schema) *Schema {
+	const prefix = "#/components/schemas/"
+	for i := 0; s != nil && s.Ref != "" && i < 32; i++ {
+		if !strings.HasPrefix(s.Ref, prefix) || doc.Components == nil {
+			return nil
+		}
+		s = doc.Components.Schemas[s.Ref[len(prefix):]]
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+const petstore = `
+openapi: 3.1.0
+info:
+  title: Petstore
+  version: "1.0"
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: [integer, "null"]
+            maximum: 100
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Pet"
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+          format: int64
+    get:
+      operationId: getPet
+      summary: Info for a specific pet
+      tags: [pets]
+    delete:
+      operationId: deletePet
+  /pets/{kind}/photos/{name}:
+    get:
+      operationId: getPhoto
+      parameters:
+        - name: kind
+          in: path
+          required: true
+          schema:
+            $ref: "#/components/schemas/Kind"
+        - name: name
+          in: path
+          required: true
+          schema:
+            type: string
+            pattern: "^[a-z]+\\.jpg$"
+  /stores/{storeId}:cancel:
+    post:
+      operationId: cancelStore
+      parameters:
+        - name: storeId
+          in: path
+          required: true
+          schema:
+            type: string
+            format: uuid
+  /owners:
+    get:
+      summary: no operation id
+components:
+  schemas:
+    Kind:
+      type: string
+      enum: [cat, dog]
+    Pet:
+      type: object
+      required: [name]
+      additionalProperties: false
+      properties:
+        name:
+          type: string
+`
+
+func TestOpenAPIRoutes(t *testing.T) {
+	doc, err := apirouter.LoadOpenAPI([]byte(petstore))
+	assert.NoError(t, err)
+	assert.Equal(t, "integer", doc.Paths["/pets"].Get.Parameters[0].Schema.Type)
+	assert.True(t, doc.Paths["/pets"].Get.Parameters[0].Schema.Nullable)
+	assert.NotNil(t, doc.Components.Schemas["Pet"].AdditionalProperties.Not)
+
+	routes, err := apirouter.OpenAPIRoutes(doc, map[string]apirouter.Handler{
+		"listPets":    writeString("list"),
+		"createPet":   writeString("create"),
+		"getPet":      writeString("get"),
+		"getPhoto":    writeString("photo"),
+		"cancelStore": writeString("cancel"),
+		"updatePet":   writeString("update"),
+	})
+	assert.Equal(t, &apirouter.BindError{
+		Missing: []string{"GET /owners", "deletePet"},
+		Extra:   []string{"updatePet"},
+	}, err)
+
+	r := apirouter.New(routes)
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/pets", 200, "list"},
+		{"POST", "/pets", 200, "create"},
+		{"GET", "/pets/12", 200, "get:petId=12"},
+		{"GET", "/pets/cat", 404, ""},
+		{"DELETE", "/pets/12", 404, ""},
+		{"GET", "/pets/dog/photos/a.jpg", 200, "photo:kind=dog:name=a.jpg"},
+		{"GET", "/pets/cow/photos/a.jpg", 404, ""},
+		{"GET", "/pets/dog/photos/a.png", 404, ""},
+		{"POST", "/stores/123e4567-e89b-12d3-a456-426614174000:cancel", 200,
+			"cancel:storeId=123e4567-e89b-12d3-a456-426614174000"},
+		{"POST", "/stores/1:cancel", 404, ""},
+		{"GET", "/owners", 404, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.path)
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		if tt.status == 200 {
+			assert.Equal(t, tt.body, w.Body.String(), tt.path)
+		}
+	}
+
+	infos := r.Routes()
+	assert.Equal(t, 5, len(infos))
+	for _, info := range infos {
+		if info.OperationID == "getPet" {
+			assert.Equal(t, "/pets/{petId}", info.Pattern.Pattern())
+		}
+	}
+	assert.Equal(t, []string{"pets"}, r.OpenAPI(doc.Info).Paths["/pets/{petId}"].Get.Tags)
+}
+
+func TestOpenAPIRoutesInvalid(t *testing.T) {
+	_, err := apirouter.LoadOpenAPI([]byte(`swagger: "2.0"`))
+	assert.Error(t, err)
+
+	doc, err := apirouter.LoadOpenAPI([]byte(`{"openapi": "3.0.3", "info": {"title": "t", "version": "1"},
+		"paths": {"/files/{name}.{ext}": {"get": {"operationId": "getFile"}}}}`))
+	assert.NoError(t, err)
+	_, err = apirouter.OpenAPIRoutes(doc, nil)
+	assert.Error(t, err)
+}
+
+func TestSchemaNullable(t *testing.T) {
+	one := 1
+	s := &apirouter.Schema{Type: "string", Nullable: true, MinLength: &one}
+
+	data, err := json.Marshal(map[string]*apirouter.Schema{"name": s})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":{"type":["string","null"],"minLength":1}}`, string(data))
+	var decoded map[string]*apirouter.Schema
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, s, decoded["name"])
+
+	data, err = yaml.Marshal(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "type:\n- string\n- \"null\"\nminLength: 1\n", string(data))
+	var fromYAML apirouter.Schema
+	assert.NoError(t, yaml.Unmarshal(data, &fromYAML))
+	assert.Equal(t, *s, fromYAML)
+
+	data, _ = json.Marshal(&apirouter.Schema{Type: "integer"})
+	assert.Equal(t, `{"type":"integer"}`, string(data))
+
+	// nullable of OpenAPI 3.0 is still read
+	var legacy apirouter.Schema
+	assert.NoError(t, yaml.Unmarshal([]byte("type: string\nnullable: true\n"), &legacy))
+	assert.Equal(t, apirouter.Schema{Type: "string", Nullable: true}, legacy)
+}
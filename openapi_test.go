@@ -0,0 +1,107 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type user struct {
+	ID       int64     `json:"id,string"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Created  time.Time `json:"created"`
+	Address  *address  `json:"address,omitempty"`
+	Friends  []*user   `json:"friends,omitempty"`
+	password string
+	Ignored  string `json:"-"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET(`/users/:id=^\d+$`, writeString("user"),
+			apirouter.OperationID("getUser"), apirouter.Summary("Get user"), apirouter.Tags("users"),
+			apirouter.ResponseType(http.StatusOK, user{}),
+			apirouter.ResponseType(http.StatusNotFound, nil)),
+		apirouter.POST("/users", writeString("create"),
+			apirouter.RequestType(&user{}), apirouter.ResponseType(http.StatusCreated, user{}),
+			apirouter.MatchHeader("X-Tenant", "a")),
+		apirouter.GET("/files/*path", writeString("file"), apirouter.MatchQuery("dl", "1", "0")),
+		apirouter.GET("/names/:name=^[a-z]+$/*", writeString("name")),
+		apirouter.ServeOpenAPI("/openapi.json", apirouter.Info{Title: "test", Version: "1.0"}),
+	)
+
+	doc := r.OpenAPI(apirouter.Info{Title: "test", Version: "1.0"})
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Equal(t, 4, len(doc.Paths))
+
+	op := doc.Paths["/users/{id}"].Get
+	assert.Equal(t, "getUser", op.OperationID)
+	assert.Equal(t, []string{"users"}, op.Tags)
+	assert.Equal(t, &apirouter.Parameter{Name: "id", In: "path", Required: true,
+		Schema: &apirouter.Schema{Type: "integer"}}, op.Parameters[0])
+	assert.Equal(t, "#/components/schemas/user", op.Responses["200"].Content["application/json"].Schema.Ref)
+	assert.Equal(t, "Not Found", op.Responses["404"].Description)
+	assert.Nil(t, op.Responses["404"].Content)
+
+	op = doc.Paths["/users"].Post
+	assert.Equal(t, "#/components/schemas/user", op.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "header", op.Parameters[0].In)
+	assert.Equal(t, []interface{}{"a"}, op.Parameters[0].Schema.Enum)
+
+	op = doc.Paths["/files/{path}"].Get
+	assert.Equal(t, 2, len(op.Parameters))
+	assert.Equal(t, "query", op.Parameters[1].In)
+	assert.Equal(t, "OK", op.Responses["200"].Description)
+
+	op = doc.Paths["/names/{name}/{param2}"].Get
+	assert.Equal(t, "^[a-z]+$", op.Parameters[0].Schema.Pattern)
+
+	s := doc.Components.Schemas["user"]
+	assert.Equal(t, []string{"id", "name", "created"}, s.Required)
+	assert.Equal(t, "string", s.Properties["id"].Type)
+	assert.Equal(t, "date-time", s.Properties["created"].Format)
+	assert.Equal(t, "#/components/schemas/address", s.Properties["address"].Ref)
+	assert.Equal(t, "#/components/schemas/user", s.Properties["friends"].Items.Ref)
+	assert.Nil(t, s.Properties["password"])
+	assert.Nil(t, s.Properties["Ignored"])
+	assert.Equal(t, 7, len(s.Properties))
+
+	w := serve(r, "GET", "/openapi.json")
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var served apirouter.OpenAPI
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, doc, &served)
+}
+
+func TestOpenAPI_gRPC(t *testing.T) {
+	r := apirouter.NewForGRPC(
+		apirouter.GET("/v1/shelves/{shelf}", writeString("shelf")),
+		apirouter.POST("/v1/books/{book.id}:publish", writeString("publish")),
+		apirouter.GET("/v1/images/**", writeString("image")),
+		apirouter.ServeOpenAPI("/openapi.yaml", apirouter.Info{Title: "test", Version: "1.0"}),
+	)
+
+	doc := r.OpenAPI(apirouter.Info{Title: "test", Version: "1.0"})
+	assert.NotNil(t, doc.Paths["/v1/books/{book.id}:publish"].Post)
+	assert.NotNil(t, doc.Paths["/v1/images/{param1}"].Get)
+
+	w := serve(r, "GET", "/openapi.yaml")
+	assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "openapi: 3.1.0\n"))
+	assert.Contains(t, w.Body.String(), "/v1/books/{book.id}:publish:")
+}
@@ -5,12 +5,9 @@
 package apirouter
 
 import (
-	"context"
 	"fmt"
 	"net/http"
-	"reflect"
 	"strings"
-	"unsafe"
 )
 
 // Option represents all possible options to the New() function
@@ -24,6 +21,66 @@ func (f optionFunc) apply(r *Router) {
 	f(r)
 }
 
+// PanicHandler creates the option to set a handler
+// to handle panics recovered from http handlers.
+//
+// The handler should be used to generate an error page and return
+// the http error code 500 (Internal Server Error).
+// The value of http.ErrAbortHandler is not recovered, the panic
+// is propagated to abort the response.
+func PanicHandler(handler func(w http.ResponseWriter, r *http.Request, recovered interface{})) Option {
+	if handler == nil {
+		panic("router: nil handler")
+	}
+
+	return optionFunc(func(r *Router) {
+		r.panicHandler = handler
+	})
+}
+
+// PathValues creates the option to set the named path parameters
+// as the request's path values for all routes (Go 1.22+),
+// so that the handlers migrated from http.ServeMux can call r.PathValue unmodified.
+//
+// The routes registered with Handle or HandleFunc always set the path values.
+func PathValues() Option {
+	return optionFunc(func(r *Router) {
+		r.pathValues = true
+	})
+}
+
+// RouteOption represents all possible options to the route
+// registered by API, Handle or HandleFunc.
+type RouteOption interface {
+	applyRoute(*route)
+}
+
+type routeOptionFunc func(*route)
+
+func (f routeOptionFunc) applyRoute(rt *route) {
+	f(rt)
+}
+
+// PatternStyle creates the option to parse the patterns of the following routes
+// with the given parser, so the patterns of other syntaxes can be registered.
+//
+// It should precede the routes, the routes registered before it are not affected.
+// Example:
+//
+// 	r := apirouter.New(
+// 		apirouter.PatternStyle(parseExpress),
+// 		apirouter.GET("/users/:id(\\d+)", getUser),
+// 	)
+func PatternStyle(parser PatternParser) Option {
+	if parser == nil {
+		panic("router: nil pattern parser")
+	}
+
+	return optionFunc(func(r *Router) {
+		r.newPattern = parser
+	})
+}
+
 // NotFoundHandler creates the option to set a request handler that
 // replies to each request with a “404 page not found” reply.
 func NotFoundHandler(handler http.Handler) Option {
@@ -36,11 +93,37 @@ func NotFoundHandler(handler http.Handler) Option {
 	})
 }
 
+// Interceptors creates the option to add the global interceptors,
+// which intercept all requests dispatched by the router,
+// including the requests not found.
+//
+// The global interceptors are executed before the route's interceptors,
+// the matched route can be pulled from the request context by CurrentRoute.
+//
+// Within a Group, the interceptors only intercept the routes of the group.
+func Interceptors(its ...Interceptor) Option {
+	return optionFunc(func(r *Router) {
+		if r.scope != nil {
+			r.scope.options = append(r.scope.options, WithInterceptors(its...))
+			return
+		}
+		r.interceptors = append(r.interceptors, its...)
+	})
+}
+
+// WithInterceptors creates the route option to intercept the route handler.
+func WithInterceptors(its ...Interceptor) RouteOption {
+	return routeOptionFunc(func(rt *route) {
+		rt.interceptors = append(rt.interceptors, its...)
+	})
+}
+
 // API creates the option to registers api.
 // 	- method:  supported HTTP methods,
 // 	- pattern: url path matched pattern,
-// 	- handler: http request handler.
-func API(method string, pattern string, handler Handler) Option {
+// 	- handler: http request handler,
+// 	- options: route options, such as constraints.
+func API(method string, pattern string, handler Handler, options ...RouteOption) Option {
 	if handler == nil {
 		panic("router: nil handler")
 	}
@@ -50,88 +133,94 @@ func API(method string, pattern string, handler Handler) Option {
 	}
 
 	return optionFunc(func(r *Router) {
-		t := r.selectTree(method)
-		if t == nil {
-			panic(fmt.Errorf("router: unknown http method - %q", method))
-		}
-		p := MustPattern(r.newPattern(pattern, &t.res))
-		t.add(p, handler)
+		r.addRoute(method, pattern, r.newPattern, handler, options)
 	})
 }
 
-// GET is a shortcut for API(http.MethodGet, pattern, handler)
-func GET(pattern string, handler Handler) Option {
-	return API(http.MethodGet, pattern, handler)
+// addRoute registers the route, the pattern is parsed by the given parser.
+func (r *Router) addRoute(method string, pattern string, parse PatternParser, handler Handler, options []RouteOption) {
+	t := r.selectTree(method)
+	if t == nil {
+		panic(fmt.Errorf("router: unknown http method - %q", method))
+	}
+	if r.scope != nil {
+		pattern = r.scope.prefix + pattern
+		options = append(r.scope.options[:len(r.scope.options):len(r.scope.options)], options...)
+	}
+	rt := route{
+		method: method,
+		p:      MustPattern(parse(pattern, &t.res)),
+		h:      handler,
+	}
+	for _, opt := range options {
+		opt.applyRoute(&rt)
+	}
+	if rt.p.verb != "" {
+		t.verbFallback = true
+	}
+	r.routes = append(r.routes, rt)
+	t.add(rt)
 }
 
-// POST is a shortcut for API(http.MethodPost, pattern, handler)
-func POST(pattern string, handler Handler) Option {
-	return API(http.MethodPost, pattern, handler)
+// GET is a shortcut for API(http.MethodGet, pattern, handler, options...)
+func GET(pattern string, handler Handler, options ...RouteOption) Option {
+	return API(http.MethodGet, pattern, handler, options...)
 }
 
-// PUT is a shortcut for API(http.MethodPut, pattern, handler)
-func PUT(pattern string, handler Handler) Option {
-	return API(http.MethodPut, pattern, handler)
+// POST is a shortcut for API(http.MethodPost, pattern, handler, options...)
+func POST(pattern string, handler Handler, options ...RouteOption) Option {
+	return API(http.MethodPost, pattern, handler, options...)
 }
 
-// DELETE is a shortcut for API(http.MethodDelete, pattern, handler)
-func DELETE(pattern string, handler Handler) Option {
-	return API(http.MethodDelete, pattern, handler)
+// PUT is a shortcut for API(http.MethodPut, pattern, handler, options...)
+func PUT(pattern string, handler Handler, options ...RouteOption) Option {
+	return API(http.MethodPut, pattern, handler, options...)
 }
 
-// HEAD is a shortcut for API(http.MethodHead, pattern, handler)
-func HEAD(pattern string, handler Handler) Option {
-	return API(http.MethodHead, pattern, handler)
+// DELETE is a shortcut for API(http.MethodDelete, pattern, handler, options...)
+func DELETE(pattern string, handler Handler, options ...RouteOption) Option {
+	return API(http.MethodDelete, pattern, handler, options...)
 }
 
-// OPTIONS is a shortcut for API(http.MethodOptions, pattern, handler)
-func OPTIONS(pattern string, handler Handler) Option {
-	return API(http.MethodOptions, pattern, handler)
+// HEAD is a shortcut for API(http.MethodHead, pattern, handler, options...)
+func HEAD(pattern string, handler Handler, options ...RouteOption) Option {
+	return API(http.MethodHead, pattern, handler, options...)
 }
 
-// PATCH is a shortcut for API(http.MethodPatch, pattern, handler)
-func PATCH(pattern string, handler Handler) Option {
-	return API(http.MethodPatch, pattern, handler)
+// OPTIONS is a shortcut for API(http.MethodOptions, pattern, handler, options...)
+func OPTIONS(pattern string, handler Handler, options ...RouteOption) Option {
+	return API(http.MethodOptions, pattern, handler, options...)
 }
 
-var ctxOffset uintptr
-
-func init() {
-	var req http.Request
-	sf, _ := reflect.TypeOf(req).FieldByName("ctx")
-	ctxOffset = sf.Offset
+// PATCH is a shortcut for API(http.MethodPatch, pattern, handler, options...)
+func PATCH(pattern string, handler Handler, options ...RouteOption) Option {
+	return API(http.MethodPatch, pattern, handler, options...)
 }
 
 // Handle creates the option to perform similar actions
 // with the standard library http.Handle.
-func Handle(method string, pattern string, handler http.Handler) Option {
+//
+// The path parameters are stored in the request's context, see PathParams.
+// On Go 1.22+, they are also set as the request's path values, see http.Request.PathValue.
+func Handle(method string, pattern string, handler http.Handler, options ...RouteOption) Option {
 	if handler == nil {
 		panic("router: nil handler")
 	}
 
 	return API(method, pattern, func(w http.ResponseWriter, r *http.Request, ps Params) {
 		if ps.Count() > 0 {
-			paramsCtx := newParamsCtx(r.Context())
-			paramsCtx.params = ps
-			ctxp := (*context.Context)(unsafe.Pointer(uintptr(unsafe.Pointer(r)) + ctxOffset))
-			oldCtx := *ctxp
-			*ctxp = paramsCtx
-			defer func() {
-				*ctxp = oldCtx
-				paramsCtx.Close()
-			}()
-			handler.ServeHTTP(w, r)
+			serveWithParams(handler, w, r, ps)
 		} else {
 			handler.ServeHTTP(w, r)
 		}
-	})
+	}, options...)
 }
 
 // HandleFunc creates the option to perform similar actions
 // with the standard library http.HandleFunc.
-func HandleFunc(method string, pattern string, handler func(http.ResponseWriter, *http.Request)) Option {
+func HandleFunc(method string, pattern string, handler func(http.ResponseWriter, *http.Request), options ...RouteOption) Option {
 	if handler == nil {
 		panic("router: nil handler")
 	}
-	return Handle(method, pattern, http.HandlerFunc(handler))
+	return Handle(method, pattern, http.HandlerFunc(handler), options...)
 }
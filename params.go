@@ -6,7 +6,7 @@ package apirouter
 
 import (
 	"context"
-	"sync"
+	"net/http"
 )
 
 const (
@@ -55,22 +55,26 @@ func PathParams(c context.Context) *Params {
 	return p
 }
 
+// CurrentRoute pulls the route matched by the path from a request context,
+// or returns nil if none are present.
+//
+// It is available to the global interceptors, see Interceptors.
+// For the routes with the same pattern and different versions or constraints,
+// it is replaced by the route the request is dispatched to, such as in PostHandle.
+func CurrentRoute(c context.Context) *RouteInfo {
+	info, _ := c.Value(routeKey).(*RouteInfo)
+	return info
+}
+
 var (
-	paramsKey     = key{}
-	paramsCtxPool = sync.Pool{
-		New: func() interface{} {
-			return new(paramsCtx)
-		},
-	}
+	paramsKey = key{}
+	routeKey  = routeKeyType{}
+	ctxKey    = routeCtxKeyType{}
 )
 
 type key struct{}
-
-func newParamsCtx(parent context.Context) *paramsCtx {
-	c := paramsCtxPool.Get().(*paramsCtx)
-	c.Context = parent
-	return c
-}
+type routeKeyType struct{}
+type routeCtxKeyType struct{}
 
 type paramsCtx struct {
 	context.Context
@@ -84,8 +88,47 @@ func (c *paramsCtx) Value(key interface{}) interface{} {
 	return c.Context.Value(key)
 }
 
-func (c *paramsCtx) Close() {
-	c.Context = nil
-	c.params.names = nil
-	paramsCtxPool.Put(c)
+// routeCtx carries the router, the matched route and path parameters
+// to the global interceptors and the handlers.
+type routeCtx struct {
+	context.Context
+	router *Router
+	info   *RouteInfo
+	params Params
+}
+
+func (c *routeCtx) Value(key interface{}) interface{} {
+	switch key {
+	case paramsKey:
+		return &c.params
+	case routeKey:
+		if c.info == nil {
+			return nil
+		}
+		return c.info
+	case ctxKey:
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// setCurrentRoute replaces the route stored in the request's context,
+// such as the merged route dispatches the request to one of its candidates.
+func setCurrentRoute(r *http.Request, info *RouteInfo) {
+	if c, ok := r.Context().Value(ctxKey).(*routeCtx); ok {
+		c.info = info
+	}
+}
+
+// withParams returns a copy of r with the path parameters,
+// which are stored in its context and path values (Go 1.22+).
+func withParams(r *http.Request, ps Params) *http.Request {
+	return withPathValues(r, &paramsCtx{Context: r.Context(), params: ps}, ps)
+}
+
+// wrapPathValues wraps the handler to set the path values of request.
+func wrapPathValues(h Handler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, ps Params) {
+		h(w, withPathValues(r, r.Context(), ps), ps)
+	}
 }
@@ -0,0 +1,16 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !apirouter_unsafe
+// +build !apirouter_unsafe
+
+package apirouter
+
+import "net/http"
+
+// serveWithParams calls the handler with a shallow copy of r,
+// which carries the path parameters.
+func serveWithParams(h http.Handler, w http.ResponseWriter, r *http.Request, ps Params) {
+	h.ServeHTTP(w, withParams(r, ps))
+}
@@ -0,0 +1,23 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestLayout detects the layout changes of http.Request, which the
+// unsafe fast path depends on, it runs without the build tag apirouter_unsafe.
+func TestRequestLayout(t *testing.T) {
+	sf, found := reflect.TypeOf(http.Request{}).FieldByName("ctx")
+	if assert.True(t, found, "http.Request has no ctx field") {
+		assert.Equal(t, reflect.TypeOf((*context.Context)(nil)).Elem(), sf.Type)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build apirouter_unsafe
+// +build apirouter_unsafe
+
+package apirouter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// The fast path, enabled by the build tag apirouter_unsafe, replaces the
+// unexported ctx field and the path values of http.Request in place during
+// the handler call, instead of copying the request.
+//
+// NOTES: The handler must not retain the request after return,
+// since the context is restored and recycled.
+
+var (
+	ctxOffset   uintptr
+	ctxOffsetOK bool // the layout of http.Request is as expected
+
+	paramsCtxPool = sync.Pool{
+		New: func() interface{} {
+			return new(paramsCtx)
+		},
+	}
+)
+
+func init() {
+	sf, found := reflect.TypeOf(http.Request{}).FieldByName("ctx")
+	if found && sf.Type == reflect.TypeOf((*context.Context)(nil)).Elem() {
+		ctxOffset = sf.Offset
+		ctxOffsetOK = true
+	}
+}
+
+// serveWithParams calls the handler with r, whose context and path values are
+// temporarily replaced to carry the path parameters.
+//
+// If the layout of http.Request is changed, it falls back to the safe way.
+func serveWithParams(h http.Handler, w http.ResponseWriter, r *http.Request, ps Params) {
+	if !ctxOffsetOK {
+		h.ServeHTTP(w, withParams(r, ps))
+		return
+	}
+
+	c := paramsCtxPool.Get().(*paramsCtx)
+	c.Context = r.Context()
+	c.params = ps
+	ctxp := (*context.Context)(unsafe.Pointer(uintptr(unsafe.Pointer(r)) + ctxOffset))
+	oldCtx := *ctxp
+	*ctxp = c
+	var oldValues [maxParams]string
+	replacePathValues(r, ps, &oldValues)
+	defer func() {
+		restorePathValues(r, ps, &oldValues)
+		*ctxp = oldCtx
+		c.Context = nil
+		c.params.names = nil
+		paramsCtxPool.Put(c)
+	}()
+	h.ServeHTTP(w, r)
+}
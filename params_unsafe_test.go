@@ -0,0 +1,33 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build apirouter_unsafe
+// +build apirouter_unsafe
+
+package apirouter_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleUnsafe(t *testing.T) {
+	r := apirouter.New(
+		apirouter.HandleFunc("GET", "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(apirouter.PathParams(r.Context()).ByName("id")))
+		}),
+	)
+
+	req, _ := http.NewRequest("GET", "/users/7", nil)
+	ctx := req.Context()
+	w := serve(r, "GET", "/users/7")
+	assert.Equal(t, "7", w.Body.String())
+	r.ServeHTTP(w, req)
+	// the context is restored
+	assert.Equal(t, ctx, req.Context())
+	assert.Nil(t, apirouter.PathParams(req.Context()))
+}
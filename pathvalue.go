@@ -0,0 +1,75 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.22
+// +build go1.22
+
+package apirouter
+
+import (
+	"context"
+	"net/http"
+)
+
+// setPathValues sets the named path parameters as the request's path values,
+// so that they can be retrieved by http.Request.PathValue.
+func setPathValues(r *http.Request, ps Params) {
+	for i, name := range ps.names {
+		if name != "" {
+			r.SetPathValue(name, ps.Value(i))
+		}
+	}
+}
+
+// withPathValues returns a shallow copy of r with the context and the path values.
+//
+// The header and the forms are shared with r, but the path values of r are copied,
+// since they may be set by the outer http.ServeMux and must not be modified.
+func withPathValues(r *http.Request, ctx context.Context, ps Params) *http.Request {
+	if !hasPathValues(ps) {
+		return r.WithContext(ctx)
+	}
+
+	// Clone copies the path values, the header and the forms are detached
+	// before it and restored after it, so they are not deep copied.
+	shallow := *r
+	shallow.Header, shallow.Trailer, shallow.TransferEncoding = nil, nil, nil
+	shallow.Form, shallow.PostForm, shallow.MultipartForm = nil, nil, nil
+	r2 := shallow.Clone(ctx)
+	r2.Header, r2.Trailer, r2.TransferEncoding = r.Header, r.Trailer, r.TransferEncoding
+	r2.Form, r2.PostForm, r2.MultipartForm = r.Form, r.PostForm, r.MultipartForm
+	setPathValues(r2, ps)
+	return r2
+}
+
+// hasPathValues reports whether there are the named path parameters.
+func hasPathValues(ps Params) bool {
+	for _, name := range ps.names {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// replacePathValues sets the path values of r in place like setPathValues,
+// the old values are saved, see restorePathValues.
+func replacePathValues(r *http.Request, ps Params, old *[maxParams]string) {
+	for i, name := range ps.names {
+		if name != "" {
+			old[i] = r.PathValue(name)
+			r.SetPathValue(name, ps.Value(i))
+		}
+	}
+}
+
+// restorePathValues restores the path values replaced by replacePathValues,
+// in reverse order for the duplicate names.
+func restorePathValues(r *http.Request, ps Params, old *[maxParams]string) {
+	for i := len(ps.names) - 1; i >= 0; i-- {
+		if name := ps.names[i]; name != "" {
+			r.SetPathValue(name, old[i])
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.22
+// +build !go1.22
+
+package apirouter
+
+import (
+	"context"
+	"net/http"
+)
+
+// setPathValues is a no-op, http.Request.PathValue requires Go 1.22.
+func setPathValues(r *http.Request, ps Params) {}
+
+// withPathValues returns a shallow copy of r with the context.
+func withPathValues(r *http.Request, ctx context.Context, ps Params) *http.Request {
+	return r.WithContext(ctx)
+}
+
+// replacePathValues is a no-op, http.Request.PathValue requires Go 1.22.
+func replacePathValues(r *http.Request, ps Params, old *[maxParams]string) {}
+
+// restorePathValues is a no-op, http.Request.PathValue requires Go 1.22.
+func restorePathValues(r *http.Request, ps Params, old *[maxParams]string) {}
@@ -0,0 +1,77 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.22 && !apirouter_unsafe
+// +build go1.22,!apirouter_unsafe
+
+package apirouter_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlePathValue(t *testing.T) {
+	var retained *http.Request
+	r := apirouter.New(
+		apirouter.HandleFunc("GET", "/users/:id/files/*path", func(w http.ResponseWriter, r *http.Request) {
+			retained = r
+			r.Header.Set("X-Seen", "1")
+			w.Write([]byte(r.PathValue("id") + " " + r.PathValue("path")))
+		}),
+	)
+
+	req, _ := http.NewRequest("GET", "/users/7/files/a/b.txt", nil)
+	w := serve(r, "GET", "/users/7/files/a/b.txt")
+	assert.Equal(t, "7 a/b.txt", w.Body.String())
+
+	// the path values are set on a copy, the request passed to ServeHTTP is not modified
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "7", retained.PathValue("id"))
+	assert.Equal(t, "7", apirouter.PathParams(retained.Context()).ByName("id"))
+	assert.Empty(t, req.PathValue("id"))
+	// the copy is shallow, the header is shared
+	assert.Equal(t, "1", req.Header.Get("X-Seen"))
+}
+
+func TestPathValues(t *testing.T) {
+	r := apirouter.New(
+		apirouter.PathValues(),
+		apirouter.GET("/users/:id", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			w.Write([]byte(r.PathValue("id")))
+		}),
+	)
+	assert.Equal(t, "7", serve(r, "GET", "/users/7").Body.String())
+}
+
+func TestPathValuesUnderServeMux(t *testing.T) {
+	var inner string
+	r := apirouter.New(
+		apirouter.PathValues(),
+		apirouter.HandleFunc("GET", "/api/:id/:name", func(w http.ResponseWriter, r *http.Request) {
+			inner = r.PathValue("id") + " " + r.PathValue("name")
+		}),
+		apirouter.GET("/api/:id/:name/raw", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			inner = r.PathValue("id") + " " + r.PathValue("name")
+		}),
+	)
+	var outer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, req *http.Request) {
+		req.SetPathValue("id", "outer") // such as set by the ServeMux pattern "/api/{id}/"
+		r.ServeHTTP(w, req)
+		outer = req.PathValue("id") + " " + req.PathValue("name")
+	})
+
+	serve(mux, "GET", "/api/1/a")
+	assert.Equal(t, "1 a", inner)
+	assert.Equal(t, "outer ", outer)
+
+	serve(mux, "GET", "/api/2/b/raw")
+	assert.Equal(t, "2 b", inner)
+	assert.Equal(t, "outer ", outer)
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.22 && apirouter_unsafe
+// +build go1.22,apirouter_unsafe
+
+package apirouter_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathValuesUnsafe(t *testing.T) {
+	var inner string
+	r := apirouter.New(
+		apirouter.HandleFunc("GET", "/api/:id/:name", func(w http.ResponseWriter, r *http.Request) {
+			inner = r.PathValue("id") + " " + r.PathValue("name")
+		}),
+	)
+
+	req, _ := http.NewRequest("GET", "/api/1/a", nil)
+	req.SetPathValue("id", "outer") // such as set by the outer http.ServeMux
+	r.ServeHTTP(nil, req)
+	assert.Equal(t, "1 a", inner)
+	// the path values are restored
+	assert.Equal(t, "outer", req.PathValue("id"))
+	assert.Empty(t, req.PathValue("name"))
+}
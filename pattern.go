@@ -5,8 +5,10 @@
 package apirouter
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"unsafe"
 )
@@ -18,8 +20,38 @@ type Pattern struct {
 	fields  []string // list of fields names to be bound by this pattern
 	verb    string   // the tail static part in the pattern,eg VERB of URL path.
 	pattern string   // original pattern (example: /v1/users/{id})
+
+	res       []*regexp.Regexp // regular expressions referenced by the key
+	backtrack bool             // matched with backtracking, as the ServeMux style
+	splitVerb bool             // the verb is always split from the path, as the gRPC style
+	spans     []span           // multi-segment bindings of gRPC style
+}
+
+// span is a multi-segment binding of gRPC style, such as "{name=shelves/*/books/*}",
+// the value of its field is extended from the first parameter to the last one,
+// with the literal segments around them.
+type span struct {
+	field  int   // index of the first parameter, which is named by the field
+	last   int   // index of the last parameter
+	prefix int16 // length of the literal segments before the first parameter
+	suffix int16 // length of the literal segments after the last parameter
+}
+
+// bindSpans extends the values of the multi-segment bindings.
+func (p *Pattern) bindSpans(ps *Params) {
+	for _, sp := range p.spans {
+		ps.indices[sp.field<<1] -= sp.prefix
+		ps.indices[sp.field<<1+1] = ps.indices[sp.last<<1+1] + sp.suffix
+	}
 }
 
+// PatternParser parses the original pattern into Pattern,
+// "regexps" is a list of regular expressions shared between multiple patterns.
+//
+// NewPattern, NewGRPCPattern and NewServeMuxPattern are the built-in parsers,
+// the parsers of other syntaxes can be implemented with PatternBuilder.
+type PatternParser func(pattern string, regexps *[]*regexp.Regexp) (Pattern, error)
+
 // NewPattern creates a default style's new Pattern from the given original pattern.
 // "regexps" is a list of regular expressions shared between multiple patterns.
 //
@@ -73,24 +105,13 @@ func NewPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err error
 					err = fmt.Errorf("pattern has empty regular expression - %q", segments)
 					return
 				}
-				rec := -1 // regular expression keychar
-				for j, re := range *regexps {
-					if re.String() == expr {
-						rec = j
-						break
-					}
-				}
-				if rec == -1 { // regular expression not exist
-					var re *regexp.Regexp
-					if re, err = regexp.Compile(expr); err != nil {
-						err = fmt.Errorf("pattern has invalid regular expression - %q", segments)
-						return
-					}
-					rec = len(*regexps)
-					*regexps = append(*regexps, re)
+				rec, ok := addRegexp(regexps, expr) // regular expression keychar
+				if !ok {
+					err = fmt.Errorf("pattern has invalid regular expression - %q", segments)
+					return
 				}
 
-				kbuilder = append(kbuilder, '=', byte(rec))
+				kbuilder = append(kbuilder, '=', rec)
 			}
 		} else if c == '*' { // wildcard parameter
 			m := strings.IndexByte(segments[i:], '/')
@@ -107,6 +128,7 @@ func NewPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err error
 		key:     *(*string)(unsafe.Pointer(&kbuilder)),
 		fields:  fields,
 		pattern: pattern,
+		res:     regexpsOf(regexps),
 	}, nil
 }
 
@@ -121,12 +143,19 @@ func NewPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err error
 // 	Parameter	= Anonymous | Named
 // 	Anonymous	= "*" | "**"
 // 	Named		= "{" FieldPath [ "=" Wildcard ] "}"
-// 	Wildcard	= "*" | "**" | Regexp
+// 	Wildcard	= "*" | "**" | Regexp | Binding
+// 	Binding		= BSegment "/" BSegment { "/" BSegment }
+// 	BSegment	= LITERAL | "*" | "**"
 // 	FieldPath	= IDENT { "." IDENT } 
 // 	Verb		= ":" LITERAL 
 //
+// The multi-segment Binding has at least one "*" or "**", such as "/v1/{name=messages/*}",
+// the field is bound to all of its segments, such as "messages/1".
+// The extra "*" of the Binding are the anonymous parameters.
+//
 func NewGRPCPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err error) {
 	var fields []string
+	var spans []span
 	kbuilder := make([]byte, 0, len(pattern)+1)
 	segments, verb := splitURLPath(pattern)
 
@@ -149,6 +178,13 @@ func NewGRPCPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err e
 
 		begin := i
 		m := strings.IndexByte(segments[begin:], '/')
+		if c == '{' && m > 0 && segments[begin+m-1] != '}' { // multi-segment binding
+			if end := strings.Index(segments[begin:], "}/"); end > 0 {
+				m = end + 1
+			} else if segments[len(segments)-1] == '}' {
+				m = -1
+			}
+		}
 		if m < 0 { // last part
 			i = len(segments) - 1
 		} else {
@@ -191,6 +227,16 @@ func NewGRPCPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err e
 			expr = segment[nvSep+1:]
 		}
 
+		if strings.IndexByte(expr, '/') >= 0 {
+			sp, e := bindSegments(name, expr, m > 0, &kbuilder, &fields)
+			if e != nil {
+				err = fmt.Errorf("pattern %v - %q", e, segments)
+				return
+			}
+			spans = append(spans, sp)
+			continue
+		}
+
 		fields = append(fields, name)
 		switch expr {
 		case "*": //named parameter
@@ -206,24 +252,13 @@ func NewGRPCPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err e
 				err = fmt.Errorf("pattern has empty regular expression - %q", segments)
 				return
 			}
-			rec := -1 // regular expression keychar
-			for j, re := range *regexps {
-				if re.String() == expr {
-					rec = j
-					break
-				}
-			}
-			if rec == -1 { // regular expression not exist
-				var re *regexp.Regexp
-				if re, err = regexp.Compile(expr); err != nil {
-					err = fmt.Errorf("pattern has invalid regular expression - %q", segments)
-					return
-				}
-				rec = len(*regexps)
-				*regexps = append(*regexps, re)
+			rec, ok := addRegexp(regexps, expr) // regular expression keychar
+			if !ok {
+				err = fmt.Errorf("pattern has invalid regular expression - %q", segments)
+				return
 			}
 
-			kbuilder = append(kbuilder, ':', '=', byte(rec))
+			kbuilder = append(kbuilder, ':', '=', rec)
 		}
 	}
 	if verb != "" {
@@ -231,13 +266,63 @@ func NewGRPCPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err e
 	}
 
 	return Pattern{
-		key:     *(*string)(unsafe.Pointer(&kbuilder)),
-		fields:  fields,
-		verb:    verb,
-		pattern: pattern,
+		key:       *(*string)(unsafe.Pointer(&kbuilder)),
+		fields:    fields,
+		verb:      verb,
+		pattern:   pattern,
+		res:       regexpsOf(regexps),
+		spans:     spans,
+		splitVerb: true,
 	}, nil
 }
 
+// bindSegments appends the key and fields of the multi-segment binding, such as "messages/*",
+// more reports whether the binding is followed by more segments.
+func bindSegments(name, expr string, more bool, key *[]byte, fields *[]string) (sp span, err error) {
+	sp.field = -1
+	parts := strings.Split(expr, "/")
+	for k, part := range parts {
+		if k > 0 {
+			*key = append(*key, '/')
+		}
+		switch part {
+		case "":
+			return sp, errors.New("binding include empty segment")
+		case "*", "**":
+			if part == "**" && (more || k < len(parts)-1) {
+				return sp, errors.New("'**' in binding must is last segment")
+			}
+			if sp.field < 0 {
+				sp.field = len(*fields)
+				*fields = append(*fields, name)
+			} else {
+				*fields = append(*fields, "")
+			}
+			sp.last = len(*fields) - 1
+			sp.suffix = 0
+			if part == "*" {
+				*key = append(*key, ':')
+			} else {
+				*key = append(*key, '*')
+			}
+		default:
+			if strings.ContainsAny(part, "{}*=:") {
+				return sp, errors.New("binding has invalid segment " + strconv.Quote(part))
+			}
+			*key = append(*key, part...)
+			if sp.field < 0 {
+				sp.prefix += int16(len(part) + 1)
+			} else {
+				sp.suffix += int16(len(part) + 1)
+			}
+		}
+	}
+	if sp.field < 0 {
+		return sp, errors.New("binding has no '*' or '**'")
+	}
+	return
+}
+
 // MustPattern is a helper function which makes it easier to call NewPattern or NewGRPCPattern in variable initialization.
 func MustPattern(p Pattern, err error) Pattern {
 	if err != nil {
@@ -261,6 +346,60 @@ func (p Pattern) Verb() string { return p.verb }
 // Pattern returns the original pattern (example: /v1/users/{id})
 func (p Pattern) Pattern() string { return p.pattern }
 
+// Match reports whether the path matches the pattern, and returns the path parameters.
+//
+// The semantics are the same as the router of the pattern style with only this pattern:
+// the regular expressions are checked, the wildcard matches the rest of path,
+// the verb of the path is always split for the gRPC style as NewForGRPC,
+// otherwise it is split only if the pattern has verb, and the full path is
+// matched if the verb does not match.
+func (p Pattern) Match(path string) (ps Params, ok bool) {
+	if p.key == "" || len(p.fields) > maxParams {
+		return
+	}
+
+	t := tree{
+		res:          p.res,
+		supportVerb:  p.splitVerb,
+		verbFallback: p.verb != "",
+		servemux:     p.backtrack,
+		sep:          p.key[0],
+	}
+	t.add(route{p: p})
+	t.init(nil)
+	if t.match(path, &ps) == nil {
+		return Params{}, false
+	}
+	return ps, true
+}
+
+// regexpsOf returns the current regular expressions of the shared list.
+func regexpsOf(regexps *[]*regexp.Regexp) []*regexp.Regexp {
+	if regexps == nil {
+		return nil
+	}
+	return *regexps
+}
+
+// addRegexp adds the regular expression to the shared list if it does not exist,
+// and returns its index.
+func addRegexp(regexps *[]*regexp.Regexp, expr string) (rec byte, ok bool) {
+	for j, re := range *regexps {
+		if re.String() == expr {
+			return byte(j), true
+		}
+	}
+	if len(*regexps) > 0xff {
+		return 0, false
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return 0, false
+	}
+	*regexps = append(*regexps, re)
+	return byte(len(*regexps) - 1), true
+}
+
 func splitURLPath(path string) (segments, verb string) {
 	for i := len(path) - 1; i >= 0 && path[i] != '/'; i-- {
 		if path[i] == ':' {
@@ -0,0 +1,111 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternMatch(t *testing.T) {
+	var res []*regexp.Regexp
+	p := apirouter.MustPattern(apirouter.NewGRPCPattern("/v1/{name=^shelves$}/{id}/**:get", &res))
+	ps, ok := p.Match("/v1/shelves/1/books/2:get")
+	assert.True(t, ok)
+	assert.Equal(t, 3, ps.Count())
+	assert.Equal(t, "1", ps.ByName("id"))
+	assert.Equal(t, "books/2", ps.Value(2))
+	_, ok = p.Match("/v1/shelves/1/books/2")
+	assert.False(t, ok)
+	_, ok = p.Match("/v1/authors/1/books/2:get")
+	assert.False(t, ok)
+}
+
+func TestPatternSegments(t *testing.T) {
+	var res []*regexp.Regexp
+	p := apirouter.MustPattern(apirouter.NewGRPCPattern("/v1/{name=^shelves$}/{id}/**:get", &res))
+	segs := p.Segments()
+	if assert.Equal(t, 4, len(segs)) {
+		assert.Equal(t, apirouter.LiteralSegment, segs[0].Kind)
+		assert.Equal(t, "v1", segs[0].Text)
+		assert.Equal(t, apirouter.RegexpSegment, segs[1].Kind)
+		assert.Equal(t, "^shelves$", segs[1].Regexp.String())
+		assert.Equal(t, apirouter.ParamSegment, segs[2].Kind)
+		assert.Equal(t, apirouter.WildcardSegment, segs[3].Kind)
+	}
+	assert.Equal(t, "regular expression parameter", segs[1].Kind.String())
+
+	p = apirouter.MustPattern(apirouter.NewPattern("/", &res))
+	assert.Equal(t, []apirouter.PatternSegment{{Kind: apirouter.LiteralSegment}}, p.Segments())
+}
+
+// TestPatternMatchRouter checks Pattern.Match against the router with only the pattern.
+func TestPatternMatchRouter(t *testing.T) {
+	tests := []struct {
+		style    string
+		patterns []string
+		paths    []string
+	}{
+		{"default",
+			[]string{"/", "/users", "/users/", "/users/:id", `/users/:id=^\d+$/books`, "/files/*path", "/a/:b/*c", "/a:b/c"},
+			[]string{"/", "/users", "/users/", "/users/1", "/users/tom", "/users/1/books", "/users/tom/books",
+				"/files", "/files/", "/files/a/b", "/a/b", "/a/b/", "/a/b/c/d", "/a:b/c", "/users/:", "//",
+				"/users/a:b", "/files/a:b"}},
+		{"grpc",
+			[]string{"/v1/{id}", "/v1/{id}:cancel", "/v1/*/{name=**}", "/v1/{id=^[a-z]+$}:get", "/v1/users/{id}/*:run",
+				"/v2/{name=shelves/*/books/*}:get", "/v2/{name=messages/*}/{id}"},
+			[]string{"/v1/1", "/v1/1:cancel", "/v1/1:get", "/v1/a:get", "/v1/a/b/c", "/v1/a/", "/v1/users/1/x:run",
+				"/v1/users/1/x", "/v1/:cancel", "/v1", "/v2/shelves/1/books/2:get", "/v2/shelves/1/books/2",
+				"/v2/messages/1/2", "/v2/messages/1", "/v1/a:b:get", "/v1/:get"}},
+		{"servemux",
+			[]string{"/", "/static/", "/items/{id}", "/items/{id}/{$}", "/files/{path...}", "/a/{x}/b"},
+			[]string{"/", "/x", "/static/", "/static/a/b", "/items/1", "/items/", "/items/1/", "/files/",
+				"/files/a/b", "/a/x/b", "/a//b"}},
+	}
+
+	for _, tt := range tests {
+		parse := apirouter.NewPattern
+		switch tt.style {
+		case "grpc":
+			parse = apirouter.NewGRPCPattern
+		case "servemux":
+			parse = apirouter.NewServeMuxPattern
+		}
+
+		for _, pattern := range tt.patterns {
+			var r *apirouter.Router
+			switch tt.style {
+			case "grpc":
+				r = apirouter.NewForGRPC(apirouter.GET(pattern, writeString("")))
+			case "servemux":
+				r = apirouter.NewForServeMux(apirouter.GET(pattern, writeString("")))
+			default:
+				r = apirouter.New(apirouter.GET(pattern, writeString("")))
+			}
+			var res []*regexp.Regexp
+			p := apirouter.MustPattern(parse(pattern, &res))
+
+			for _, path := range tt.paths {
+				w := serve(r, "GET", path)
+				ps, ok := p.Match(path)
+				assert.Equal(t, w.Code == http.StatusOK, ok, "%s %s", pattern, path)
+				if ok {
+					assert.Equal(t, w.Body.String(), writeParams(ps), "%s %s", pattern, path)
+				}
+			}
+		}
+	}
+}
+
+func writeParams(ps apirouter.Params) (s string) {
+	for i := 0; i < ps.Count(); i++ {
+		s += ":" + ps.Name(i) + "=" + ps.Value(i)
+	}
+	return
+}
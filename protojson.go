@@ -0,0 +1,761 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The protobuf JSON mapping of the Go structs, see https://protobuf.dev/programming-guides/proto3/#json.
+//
+// The field names are taken from the "protobuf" tag generated by protoc-gen-go,
+// or the "json" tag, or the Go field name. Both the original name and
+// the lowerCamelCase name are accepted, and the lowerCamelCase name is emitted.
+// The 64-bit integers are emitted as strings, and the fields of default value are omitted.
+// The enums are emitted as names, and both names and numbers are accepted.
+// Timestamp, Duration and the wrappers are mapped to their JSON forms,
+// the other well-known types such as Any and Struct, and the oneofs are not supported.
+//
+// The enums and well-known types are recognized by the methods generated by protoc-gen-go,
+// such as Descriptor of the enums and ProtoReflect of the messages, through reflection.
+
+// protoField is a field of the Go struct of protobuf message.
+type protoField struct {
+	index    int
+	name     string // original name, such as "page_size"
+	jsonName string // lowerCamelCase name, such as "pageSize"
+}
+
+var protoFieldsCache sync.Map // map[reflect.Type][]protoField
+
+func protoFields(t reflect.Type) []protoField {
+	if fs, ok := protoFieldsCache.Load(t); ok {
+		return fs.([]protoField)
+	}
+
+	var fs []protoField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || sf.Tag.Get("protobuf_oneof") != "" {
+			continue
+		}
+
+		f := protoField{index: i}
+		for _, s := range strings.Split(sf.Tag.Get("protobuf"), ",") {
+			if strings.HasPrefix(s, "name=") {
+				f.name = s[5:]
+			} else if strings.HasPrefix(s, "json=") {
+				f.jsonName = s[5:]
+			}
+		}
+		if f.name == "" {
+			f.name = strings.Split(sf.Tag.Get("json"), ",")[0]
+			if f.name == "-" {
+				continue
+			}
+			if f.name == "" {
+				f.name = sf.Name
+			}
+		}
+		if f.jsonName == "" {
+			f.jsonName = lowerCamelCase(f.name)
+		}
+		fs = append(fs, f)
+	}
+	protoFieldsCache.Store(t, fs)
+	return fs
+}
+
+func lookupProtoField(t reflect.Type, name string) (protoField, bool) {
+	for _, f := range protoFields(t) {
+		if f.name == name || f.jsonName == name {
+			return f, true
+		}
+	}
+	return protoField{}, false
+}
+
+// lowerCamelCase converts the name to lowerCamelCase,
+// such as "page_size" to "pageSize" and "URLPath" to "urlPath".
+func lowerCamelCase(name string) string {
+	b := []byte(name)
+	for i := 0; i < len(b) && isUpper(b[i]); i++ {
+		if i > 0 && i+1 < len(b) && isLower(b[i+1]) {
+			break // the beginning of next word
+		}
+		b[i] += 'a' - 'A'
+	}
+
+	out := b[:0]
+	for i := 0; i < len(b); i++ {
+		if b[i] == '_' && i+1 < len(b) && isLower(b[i+1]) {
+			b[i+1] -= 'a' - 'A'
+			continue
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
+
+func isUpper(c byte) bool { return c >= 'A' && c <= 'Z' }
+func isLower(c byte) bool { return c >= 'a' && c <= 'z' }
+
+// protoFieldByPath returns the field of the path, such as "book.author.name",
+// the nil pointers on the way are allocated. v must be addressable.
+func protoFieldByPath(v reflect.Value, path string) (reflect.Value, bool) {
+	for {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		name, rest := path, ""
+		if i := strings.IndexByte(path, '.'); i >= 0 {
+			name, rest = path[:i], path[i+1:]
+		}
+		f, ok := lookupProtoField(v.Type(), name)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		v = v.Field(f.index)
+		if rest == "" {
+			return v, true
+		}
+		path = rest
+	}
+}
+
+// setProtoValues sets the field to the values of the path or query parameter,
+// the repeated field takes all the values, the others take the last one.
+func setProtoValues(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for _, s := range values {
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := setProtoScalar(e, s); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, e))
+		}
+		return nil
+	}
+	return setProtoScalar(v, values[len(values)-1])
+}
+
+// setProtoScalar sets the scalar field from its string form.
+func setProtoScalar(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if name, kind := wellKnownProto(v.Type()); kind != notWellKnown {
+		return setWellKnownProto(v, name, kind, s)
+	}
+	if e := protoEnumOf(v.Type()); e != nil {
+		if n, ok := e.numbers[s]; ok {
+			v.SetInt(n)
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err == nil {
+			v.SetBool(b)
+		}
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err == nil {
+			v.SetInt(i)
+		}
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err == nil {
+			v.SetUint(u)
+		}
+		return err
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		var err error
+		switch s {
+		case "NaN":
+			f = math.NaN()
+		case "Infinity":
+			f = math.Inf(1)
+		case "-Infinity":
+			f = math.Inf(-1)
+		default:
+			f, err = strconv.ParseFloat(s, v.Type().Bits())
+		}
+		if err == nil {
+			v.SetFloat(f)
+		}
+		return err
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data, err := decodeBase64(s)
+			if err == nil {
+				v.SetBytes(data)
+			}
+			return err
+		}
+	}
+	return fmt.Errorf("cannot set %s from string", v.Type())
+}
+
+// decodeBase64 accepts both the standard and the URL-safe encoding, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	enc := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	if len(s)%4 != 0 {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	return enc.DecodeString(s)
+}
+
+// unmarshalProtoJSON decodes the JSON to v, v must be addressable.
+func unmarshalProtoJSON(data []byte, v reflect.Value) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var x interface{}
+	if err := dec.Decode(&x); err != nil {
+		return err
+	}
+	return assignProto(v, x, "")
+}
+
+// assignProto assigns the decoded JSON value to v, path is the field path for errors.
+func assignProto(v reflect.Value, x interface{}, path string) error {
+	if x == nil { // null is the default value
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() != reflect.Ptr {
+		if u, ok := v.Addr().Interface().(json.Unmarshaler); ok {
+			data, _ := json.Marshal(x)
+			return u.UnmarshalJSON(data)
+		}
+	}
+
+	invalid := func() error {
+		if path == "" {
+			return fmt.Errorf("invalid value for %s", v.Type())
+		}
+		return fmt.Errorf("invalid value for field %q", path)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return assignProto(v.Elem(), x, path)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return invalid()
+		}
+		v.Set(reflect.ValueOf(x))
+		return nil
+	case reflect.Struct:
+		if name, kind := wellKnownProto(v.Type()); kind != notWellKnown {
+			switch kind {
+			case wrapperType:
+				return assignProto(v.FieldByName("Value"), x, path)
+			case unsupportedType:
+				return unsupportedProto(name)
+			}
+			if s, ok := x.(string); !ok || setWellKnownProto(v, name, kind, s) != nil {
+				return invalid()
+			}
+			return nil
+		}
+		obj, ok := x.(map[string]interface{})
+		if !ok {
+			return invalid()
+		}
+		for name, val := range obj {
+			f, ok := lookupProtoField(v.Type(), name)
+			if !ok {
+				return fmt.Errorf("unknown field %q", joinFieldPath(path, name))
+			}
+			if err := assignProto(v.Field(f.index), val, joinFieldPath(path, f.jsonName)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		obj, ok := x.(map[string]interface{})
+		if !ok {
+			return invalid()
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(obj)))
+		}
+		for key, val := range obj {
+			k := reflect.New(v.Type().Key()).Elem()
+			if err := setProtoScalar(k, key); err != nil {
+				return invalid()
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := assignProto(e, val, joinFieldPath(path, key)); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, e)
+		}
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		arr, ok := x.([]interface{})
+		if !ok {
+			return invalid()
+		}
+		s := reflect.MakeSlice(v.Type(), len(arr), len(arr))
+		for i, val := range arr {
+			if err := assignProto(s.Index(i), val, path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	var s string
+	switch x := x.(type) {
+	case string:
+		s = x
+	case json.Number:
+		if v.Kind() == reflect.String || v.Kind() == reflect.Slice {
+			return invalid()
+		}
+		s = string(x)
+	case bool:
+		if v.Kind() != reflect.Bool {
+			return invalid()
+		}
+		v.SetBool(x)
+		return nil
+	default:
+		return invalid()
+	}
+	if setProtoScalar(v, s) != nil {
+		return invalid()
+	}
+	return nil
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// marshalProtoJSON encodes v to JSON.
+func marshalProtoJSON(v reflect.Value) ([]byte, error) {
+	var b bytes.Buffer
+	err := encodeProto(&b, v)
+	return b.Bytes(), err
+}
+
+func encodeProto(b *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() || ((v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil()) {
+		b.WriteString("null")
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return encodeJSON(b, v.Interface())
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return encodeProto(b, v.Elem())
+	case reflect.Int64, reflect.Int:
+		b.WriteByte('"')
+		b.WriteString(strconv.FormatInt(v.Int(), 10))
+		b.WriteByte('"')
+	case reflect.Uint64, reflect.Uint:
+		b.WriteByte('"')
+		b.WriteString(strconv.FormatUint(v.Uint(), 10))
+		b.WriteByte('"')
+	case reflect.Int32:
+		if e := protoEnumOf(v.Type()); e != nil {
+			if name, ok := e.names[v.Int()]; ok {
+				return encodeJSON(b, name)
+			}
+		}
+		return encodeJSON(b, v.Interface()) // the unknown enum value as number
+	case reflect.Float32, reflect.Float64:
+		switch f := v.Float(); {
+		case math.IsNaN(f):
+			b.WriteString(`"NaN"`)
+		case math.IsInf(f, 1):
+			b.WriteString(`"Infinity"`)
+		case math.IsInf(f, -1):
+			b.WriteString(`"-Infinity"`)
+		default:
+			return encodeJSON(b, v.Interface())
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return encodeJSON(b, v.Interface()) // base64
+		}
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := encodeProto(b, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			k := fmt.Sprint(it.Key().Interface())
+			keys = append(keys, k)
+			values[k] = it.Value()
+		}
+		sort.Strings(keys)
+
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			encodeJSON(b, k)
+			b.WriteByte(':')
+			if err := encodeProto(b, values[k]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case reflect.Struct:
+		if name, kind := wellKnownProto(v.Type()); kind != notWellKnown {
+			return encodeWellKnownProto(b, v, name, kind)
+		}
+		b.WriteByte('{')
+		first := true
+		for _, f := range protoFields(v.Type()) {
+			fv := v.Field(f.index)
+			if isProtoDefault(fv) {
+				continue
+			}
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			encodeJSON(b, f.jsonName)
+			b.WriteByte(':')
+			if err := encodeProto(b, fv); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return encodeJSON(b, v.Interface())
+	}
+	return nil
+}
+
+func encodeJSON(b *bytes.Buffer, v interface{}) error {
+	data, err := json.Marshal(v)
+	b.Write(data)
+	return err
+}
+
+// isProtoDefault reports whether the field has the default value, which is omitted.
+func isProtoDefault(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// protoEnum is the names and numbers of the enum type generated by protoc-gen-go.
+type protoEnum struct {
+	names   map[int64]string
+	numbers map[string]int64
+}
+
+var protoEnumCache sync.Map // map[reflect.Type]*protoEnum
+
+// protoEnumOf returns the values of the enum type by its Descriptor method, nil if t is not an enum.
+func protoEnumOf(t reflect.Type) *protoEnum {
+	if t.Kind() != reflect.Int32 {
+		return nil
+	}
+	if e, ok := protoEnumCache.Load(t); ok {
+		return e.(*protoEnum)
+	}
+
+	var e *protoEnum
+	// t.Descriptor().Values() is a protoreflect.EnumValueDescriptors
+	if values, ok := callMethods(reflect.Zero(t), "Descriptor", "Values"); ok {
+		n, ok := callMethods(values, "Len")
+		get := values.MethodByName("Get")
+		if ok && n.Kind() == reflect.Int && get.IsValid() && get.Type().NumIn() == 1 && get.Type().NumOut() == 1 {
+			e = &protoEnum{names: make(map[int64]string), numbers: make(map[string]int64)}
+			for i := 0; i < int(n.Int()); i++ {
+				value := get.Call([]reflect.Value{reflect.ValueOf(i)})[0]
+				name, ok1 := callMethods(value, "Name")
+				number, ok2 := callMethods(value, "Number")
+				if !ok1 || !ok2 || name.Kind() != reflect.String || number.Kind() != reflect.Int32 {
+					e = nil
+					break
+				}
+				if _, ok := e.names[number.Int()]; !ok { // the first of the aliases
+					e.names[number.Int()] = name.String()
+				}
+				e.numbers[name.String()] = number.Int()
+			}
+		}
+	}
+	protoEnumCache.Store(t, e)
+	return e
+}
+
+// callMethods calls the methods without arguments in chain, such as v.Descriptor().Values(),
+// it reports false if some method does not exist or returns a nil interface.
+func callMethods(v reflect.Value, names ...string) (reflect.Value, bool) {
+	for _, name := range names {
+		m := v.MethodByName(name)
+		if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+			return reflect.Value{}, false
+		}
+		v = m.Call(nil)[0]
+		if v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+	}
+	return v, true
+}
+
+// wellKnownKind is how a well-known type is mapped to JSON.
+type wellKnownKind uint8
+
+const (
+	notWellKnown    wellKnownKind = iota // mapped as the other messages
+	timestampType                        // RFC 3339 string, such as "1972-01-01T10:00:20.021Z"
+	durationType                         // seconds with the suffix "s", such as "1.000340012s"
+	wrapperType                          // the wrapped value
+	unsupportedType                      // such as Any and Struct
+)
+
+var wellKnownKinds = map[string]wellKnownKind{
+	"google.protobuf.Timestamp":   timestampType,
+	"google.protobuf.Duration":    durationType,
+	"google.protobuf.DoubleValue": wrapperType,
+	"google.protobuf.FloatValue":  wrapperType,
+	"google.protobuf.Int64Value":  wrapperType,
+	"google.protobuf.UInt64Value": wrapperType,
+	"google.protobuf.Int32Value":  wrapperType,
+	"google.protobuf.UInt32Value": wrapperType,
+	"google.protobuf.BoolValue":   wrapperType,
+	"google.protobuf.StringValue": wrapperType,
+	"google.protobuf.BytesValue":  wrapperType,
+	"google.protobuf.Any":         unsupportedType,
+	"google.protobuf.Struct":      unsupportedType,
+	"google.protobuf.Value":       unsupportedType,
+	"google.protobuf.ListValue":   unsupportedType,
+	"google.protobuf.FieldMask":   unsupportedType,
+}
+
+type wellKnownType struct {
+	name string
+	kind wellKnownKind
+}
+
+var wellKnownCache sync.Map // map[reflect.Type]wellKnownType
+
+// wellKnownProto returns the full name and the kind of the well-known type,
+// which is recognized by its ProtoReflect method.
+func wellKnownProto(t reflect.Type) (name string, kind wellKnownKind) {
+	if t.Kind() != reflect.Struct {
+		return "", notWellKnown
+	}
+	if wk, ok := wellKnownCache.Load(t); ok {
+		return wk.(wellKnownType).name, wk.(wellKnownType).kind
+	}
+
+	// (*t).ProtoReflect().Descriptor().FullName()
+	if v, ok := callMethods(reflect.New(t), "ProtoReflect", "Descriptor", "FullName"); ok && v.Kind() == reflect.String {
+		if kind = wellKnownKinds[v.String()]; kind != notWellKnown {
+			name = v.String()
+		}
+	}
+	wellKnownCache.Store(t, wellKnownType{name, kind})
+	return name, kind
+}
+
+func unsupportedProto(name string) error {
+	return fmt.Errorf("unsupported well-known type %s", name)
+}
+
+// checkProtoType returns the error if the message type t uses an unsupported well-known type.
+func checkProtoType(t reflect.Type, checked map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Map {
+		return checkProtoType(t.Elem(), checked)
+	}
+	if t.Kind() != reflect.Struct || checked[t] {
+		return nil
+	}
+	checked[t] = true
+
+	if name, kind := wellKnownProto(t); kind == unsupportedType {
+		return unsupportedProto(name)
+	}
+	for _, f := range protoFields(t) {
+		if err := checkProtoType(t.Field(f.index).Type, checked); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setWellKnownProto sets the Timestamp, Duration or wrapper from its string form.
+func setWellKnownProto(v reflect.Value, name string, kind wellKnownKind, s string) error {
+	var sec int64
+	var nanos int32
+	switch kind {
+	case wrapperType:
+		return setProtoScalar(v.FieldByName("Value"), s)
+	case timestampType:
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		sec, nanos = t.Unix(), int32(t.Nanosecond())
+	case durationType:
+		var err error
+		if sec, nanos, err = parseProtoDuration(s); err != nil {
+			return err
+		}
+	default:
+		return unsupportedProto(name)
+	}
+	v.FieldByName("Seconds").SetInt(sec)
+	v.FieldByName("Nanos").SetInt(int64(nanos))
+	return nil
+}
+
+// encodeWellKnownProto encodes the Timestamp, Duration or wrapper in its JSON form.
+func encodeWellKnownProto(b *bytes.Buffer, v reflect.Value, name string, kind wellKnownKind) error {
+	if kind == wrapperType {
+		return encodeProto(b, v.FieldByName("Value"))
+	}
+	if kind == unsupportedType {
+		return unsupportedProto(name)
+	}
+
+	sec, nanos := v.FieldByName("Seconds").Int(), int32(v.FieldByName("Nanos").Int())
+	b.WriteByte('"')
+	if kind == timestampType {
+		b.WriteString(time.Unix(sec, 0).UTC().Format("2006-01-02T15:04:05"))
+		b.WriteString(protoFraction(nanos))
+		b.WriteByte('Z')
+	} else {
+		if sec < 0 || nanos < 0 {
+			b.WriteByte('-')
+			sec, nanos = -sec, -nanos
+		}
+		b.WriteString(strconv.FormatInt(sec, 10))
+		b.WriteString(protoFraction(nanos))
+		b.WriteByte('s')
+	}
+	b.WriteByte('"')
+	return nil
+}
+
+// protoFraction formats the nanoseconds in 0, 3, 6 or 9 fractional digits.
+func protoFraction(nanos int32) string {
+	switch {
+	case nanos == 0:
+		return ""
+	case nanos%1e6 == 0:
+		return fmt.Sprintf(".%03d", nanos/1e6)
+	case nanos%1e3 == 0:
+		return fmt.Sprintf(".%06d", nanos/1e3)
+	}
+	return fmt.Sprintf(".%09d", nanos)
+}
+
+// parseProtoDuration parses the duration such as "1.5s" and "-0.000000001s".
+func parseProtoDuration(s string) (sec int64, nanos int32, err error) {
+	invalid := fmt.Errorf("invalid duration %q", s)
+	if !strings.HasSuffix(s, "s") {
+		return 0, 0, invalid
+	}
+	s = s[:len(s)-1]
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" || len(frac) > 9 || !isDigits(whole) || !isDigits(frac) {
+		return 0, 0, invalid
+	}
+	if sec, err = strconv.ParseInt(whole, 10, 64); err != nil {
+		return 0, 0, invalid
+	}
+	if frac != "" {
+		n, _ := strconv.Atoi(frac + strings.Repeat("0", 9-len(frac)))
+		nanos = int32(n)
+	}
+	if neg {
+		sec, nanos = -sec, -nanos
+	}
+	return sec, nanos, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
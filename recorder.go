@@ -0,0 +1,171 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var (
+	_ http.Flusher  = &responseRecorder{}
+	_ http.Hijacker = &responseRecorder{}
+	_ http.Pusher   = &responseRecorder{}
+	_ io.ReaderFrom = &responseRecorder{}
+
+	recorderPool = sync.Pool{
+		New: func() interface{} {
+			return new(responseRecorder)
+		},
+	}
+)
+
+// responseRecorder records the response status and size written by handler.
+//
+// It implements http.Flusher, http.Hijacker, http.Pusher and io.ReaderFrom,
+// which are delegated to the underlying ResponseWriter if supported.
+type responseRecorder struct {
+	http.ResponseWriter
+	wroteHeader bool
+	start       time.Time
+	info        HandleInfo
+	body        *bytes.Buffer // copy of the response body, nil if not required
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	rec := recorderPool.Get().(*responseRecorder)
+	rec.ResponseWriter = w
+	rec.start = time.Now()
+	return rec
+}
+
+// finish completes the handle information.
+func (rec *responseRecorder) finish() *HandleInfo {
+	if rec.info.Status == 0 {
+		rec.info.Status = http.StatusOK
+	}
+	rec.info.Duration = time.Since(rec.start)
+	return &rec.info
+}
+
+// postHandle must be deferred, it executes ex.PostHandleEx
+// with the handle information, including the recovered panic, and
+// then propagates the panic.
+func (rec *responseRecorder) postHandle(ex PostHandlerEx, r *http.Request) {
+	if v := recover(); v != nil {
+		if !rec.wroteHeader {
+			rec.info.Status = http.StatusInternalServerError
+		}
+		info := rec.finish()
+		info.Recovered = v
+		info.Stack = debug.Stack()
+		ex.PostHandleEx(rec, r, info)
+		panic(v)
+	}
+	ex.PostHandleEx(rec, r, rec.finish())
+}
+
+func (rec *responseRecorder) Close() {
+	*rec = responseRecorder{}
+	recorderPool.Put(rec)
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.wroteHeader = true
+		rec.info.Status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	n, err := rec.ResponseWriter.Write(p)
+	rec.info.Size += int64(n)
+	if rec.body != nil {
+		rec.body.Write(p[:n])
+	}
+	return n, err
+}
+
+func (rec *responseRecorder) WriteString(s string) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	n, err := io.WriteString(rec.ResponseWriter, s)
+	rec.info.Size += int64(n)
+	if rec.body != nil {
+		rec.body.WriteString(s[:n])
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher, it is a no-op
+// if the underlying ResponseWriter does not support.
+func (rec *responseRecorder) Flush() {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (rec *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rec.ResponseWriter.(http.Hijacker); ok {
+		conn, rw, err := h.Hijack()
+		if err == nil && !rec.wroteHeader {
+			rec.wroteHeader = true
+			rec.info.Status = http.StatusSwitchingProtocols
+		}
+		return conn, rw, err
+	}
+	return nil, nil, errors.New("router: the ResponseWriter does not implement http.Hijacker")
+}
+
+// Push implements http.Pusher.
+func (rec *responseRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rec.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// ReadFrom implements io.ReaderFrom.
+func (rec *responseRecorder) ReadFrom(src io.Reader) (n int64, err error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.body != nil {
+		src = io.TeeReader(src, rec.body)
+	}
+	if rf, ok := rec.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(writerOnly{rec.ResponseWriter}, src)
+	}
+	rec.info.Size += n
+	return
+}
+
+// Unwrap returns the underlying ResponseWriter, used by http.ResponseController.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// writerOnly hides the io.ReaderFrom of ResponseWriter to avoid recursion.
+type writerOnly struct {
+	io.Writer
+}
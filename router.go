@@ -105,7 +105,7 @@ package apirouter
 
 import (
 	"net/http"
-	"regexp"
+	"runtime/debug"
 )
 
 // Handler is a function that can be registered to a router to
@@ -133,7 +133,18 @@ type Router struct {
 	options tree
 
 	notFoundHandler http.Handler
-	newPattern      func(string, *[]*regexp.Regexp) (Pattern, error)
+	newPattern      PatternParser
+	versioning      versioning
+	interceptors    []Interceptor
+	interceptor     Interceptor // chain of global interceptors, nil if none
+	panicHandler    func(http.ResponseWriter, *http.Request, interface{})
+	errorHandler    func(http.ResponseWriter, *http.Request, error)
+	middlewares     []Middleware
+	handler         http.Handler                     // router wrapped by the global middlewares, nil if none
+	scope           *scope                           // the group in which the options are applying
+	pathValues      bool                             // set path parameters as request's path values
+	routes          []route                          // registered routes, in registration order
+	traceNotFound   func(*http.Request, Explanation) // called by the "not found" handler, nil if disabled
 }
 
 // New returns a new Router,which is initialized with
@@ -184,48 +195,249 @@ func NewForGRPC(options ...Option) *Router {
 //
 // If there is no registered handler that applies to the given method and path,
 // Match returns a nil handler and an empty path parameters.
+// The routes with host of NewForServeMux are not matched, since there is no host.
 func (r *Router) Match(method string, path string) (h Handler, params Params) {
 	t := r.selectTree(method)
 	if t != nil {
-		h = t.match(path, &params)
+		if r.versioning.stripPrefix {
+			_, path = splitVersionPrefix(path)
+		}
+		if rt := t.match(path, &params); rt != nil {
+			h = rt.h
+		}
 	}
 	return
 }
 
+// RouteInfo describes a route registered in the router.
+type RouteInfo struct {
+	Method       string        // HTTP method
+	Pattern      Pattern       // path pattern
+	Version      string        // API version, empty if the route is not versioned
+	Constraints  []Constraint  // additional conditions besides the path
+	Interceptors []Interceptor // interceptors in execution order, including the global ones
+	OperationID  string        // operation id in the OpenAPI document, empty if not set
+	Consumes     []string      // media types of the request body, see Consumes
+	Produces     []string      // media types of the response, see Produces
+}
+
+// Routes returns the registered routes in registration order.
+func (r *Router) Routes() []RouteInfo {
+	infos := make([]RouteInfo, len(r.routes))
+	for i := range r.routes {
+		infos[i] = r.routeInfo(&r.routes[i])
+	}
+	return infos
+}
+
+func (r *Router) routeInfo(rt *route) RouteInfo {
+	info := RouteInfo{
+		Method:       rt.method,
+		Pattern:      rt.p,
+		Constraints:  rt.constraints,
+		Interceptors: r.routeInterceptors(rt),
+		Consumes:     rt.consumes,
+		Produces:     rt.produces,
+	}
+	if rt.version != nil {
+		info.Version = rt.version.String()
+	}
+	if rt.doc != nil {
+		info.OperationID = rt.doc.id
+	}
+	return info
+}
+
 var emptyParams Params
 
 // ServeHTTP dispatches the request to the first handler
 // whose matches to req.Method and req.Path.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var h Handler
+	if r.handler != nil {
+		r.handler.ServeHTTP(w, req)
+		return
+	}
+	r.serveHTTP(w, req)
+}
+
+func (r *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.interceptor != nil || r.errorHandler != nil {
+		r.serveIntercepted(w, req)
+		return
+	}
+	if r.panicHandler != nil {
+		defer r.recoverPanic(w, req)
+	}
+
 	t := r.selectTree(req.Method)
 	if t != nil {
 		path := req.URL.Path
-		if h = t.staticMatch(path); h != nil {
-			h(w, req, emptyParams)
+		if r.versioning.stripPrefix {
+			_, path = splitVersionPrefix(path)
+		}
+		var params Params
+		if t.hosts != nil {
+			if rt := t.matchHost(req, path, &params); rt != nil {
+				rt.h(w, req, params)
+				return
+			}
+		}
+		if rt := t.staticMatch(path); rt != nil {
+			rt.h(w, req, emptyParams)
 			return
 		}
 
-		var params Params
-		h = t.patternMatch(path, &params)
-		if h != nil {
-			h(w, req, params)
+		if rt := t.patternMatch(path, &params); rt != nil {
+			rt.h(w, req, params)
 			return
 		}
 	}
 	r.notFoundHandler.ServeHTTP(w, req)
 }
 
+// serveIntercepted dispatches the request with the global interceptors if any,
+// the router and the matched route are stored in the request's context.
+func (r *Router) serveIntercepted(w http.ResponseWriter, req *http.Request) {
+	var rt *route
+	ctx := &routeCtx{Context: req.Context(), router: r}
+	if t := r.selectTree(req.Method); t != nil {
+		path := req.URL.Path
+		if r.versioning.stripPrefix {
+			_, path = splitVersionPrefix(path)
+		}
+		if t.hosts != nil {
+			rt = t.matchHost(req, path, &ctx.params)
+		}
+		if rt == nil {
+			rt = t.match(path, &ctx.params)
+		}
+		if rt != nil {
+			ctx.info = rt.info
+		}
+	}
+
+	req = req.WithContext(ctx)
+	it := r.interceptor
+	if it == nil { // the error handler only
+		r.dispatch(w, req, rt, ctx.params)
+		return
+	}
+	if ex := postHandlerEx(it); ex != nil {
+		rec := newResponseRecorder(w)
+		defer rec.Close()
+		if it.PreHandle(rec, req) {
+			defer rec.postHandle(ex, req)
+			r.dispatch(rec, req, rt, ctx.params)
+		}
+		return
+	}
+
+	if it.PreHandle(w, req) {
+		defer it.PostHandle(req)
+		r.dispatch(w, req, rt, ctx.params)
+	}
+}
+
+// dispatch dispatches the request to the matched route,
+// or to the "not found" handler if rt is nil.
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request, rt *route, params Params) {
+	if r.panicHandler != nil {
+		defer r.recoverPanic(w, req)
+	}
+	if rt != nil {
+		rt.h(w, req, params)
+	} else {
+		r.notFoundHandler.ServeHTTP(w, req)
+	}
+}
+
+// recoverPanic must be deferred, it recovers the handler panic
+// and replies with the panic handler.
+//
+// http.ErrAbortHandler is not recovered, it is used to abort the response.
+func (r *Router) recoverPanic(w http.ResponseWriter, req *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	if rec, ok := w.(*responseRecorder); ok {
+		rec.info.Recovered = v
+		rec.info.Stack = debug.Stack()
+	}
+	r.panicHandler(w, req, v)
+}
+
 func (r *Router) initTrees() {
-	r.get.init()
-	r.post.init()
-	r.delete.init()
-	r.put.init()
-	r.patch.init()
-	r.head.init()
-	r.connect.init()
-	r.trace.init()
-	r.options.init()
+	r.wrapRoutes(&r.get)
+	r.wrapRoutes(&r.post)
+	r.wrapRoutes(&r.delete)
+	r.wrapRoutes(&r.put)
+	r.wrapRoutes(&r.patch)
+	r.wrapRoutes(&r.head)
+	r.wrapRoutes(&r.connect)
+	r.wrapRoutes(&r.trace)
+	r.wrapRoutes(&r.options)
+	if it := ChainInterceptor(r.interceptors...); it != nopIt {
+		r.interceptor = it
+	}
+	if r.traceNotFound != nil {
+		r.notFoundHandler = r.traceNotFoundHandler(r.notFoundHandler)
+	}
+	if len(r.middlewares) > 0 {
+		r.handler = chainMiddlewares(http.HandlerFunc(r.serveHTTP), r.middlewares)
+	}
+
+	r.get.init(r)
+	r.post.init(r)
+	r.delete.init(r)
+	r.put.init(r)
+	r.patch.init(r)
+	r.head.init(r)
+	r.connect.init(r)
+	r.trace.init(r)
+	r.options.init(r)
+}
+
+// wrapRoutes wraps the route handlers of tree with the route's interceptors
+// and middlewares, the global ones are executed by ServeHTTP.
+func (r *Router) wrapRoutes(t *tree) {
+	for i := range t.routes {
+		rt := &t.routes[i]
+		info := r.routeInfo(rt)
+		rt.info = &info
+		if len(rt.produces) > 0 {
+			rt.h = wrapProduces(rt.h, rt.produces)
+		}
+		rt.h = wrapMiddlewares(Wrap(rt.h, rt.interceptors...), rt.middlewares)
+		if r.pathValues {
+			rt.h = wrapPathValues(rt.h)
+		}
+	}
+}
+
+// routeInterceptors returns the interceptors of the route in execution order.
+func (r *Router) routeInterceptors(rt *route) []Interceptor {
+	if len(rt.interceptors) == 0 {
+		return r.interceptors
+	}
+	its := make([]Interceptor, 0, len(r.interceptors)+len(rt.interceptors))
+	its = append(its, r.interceptors...)
+	return append(its, rt.interceptors...)
+}
+
+// reject replies to the request whose path matched,
+// but no route's constraints are satisfied.
+//
+// A nil Router replies the status only.
+func (r *Router) reject(w http.ResponseWriter, req *http.Request, status int) {
+	if r != nil && status == http.StatusNotFound {
+		r.notFoundHandler.ServeHTTP(w, req)
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
 }
 
 // selectTree returns the tree by the given HTTP method.
@@ -0,0 +1,321 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RouteTable is a serializable snapshot of the routes, which can be saved as a golden file
+// and compared with the later one by DiffRouteTables, such as:
+//
+// 	data, _ := json.MarshalIndent(r.RouteTable(), "", "  ")
+//
+// The routes are sorted by key, method and version, the order of registration does not matter.
+type RouteTable struct {
+	Routes []RouteEntry `json:"routes" yaml:"routes"`
+}
+
+// RouteEntry describes a route in the RouteTable.
+type RouteEntry struct {
+	Method      string       `json:"method" yaml:"method"`
+	Key         string       `json:"key" yaml:"key"`         // pattern without names, such as "/users/:/books/*:get"
+	Pattern     string       `json:"pattern" yaml:"pattern"` // original pattern
+	Params      []RouteParam `json:"params,omitempty" yaml:"params,omitempty"`
+	Version     string       `json:"version,omitempty" yaml:"version,omitempty"`
+	Constraints []string     `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+	OperationID string       `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Consumes    []string     `json:"consumes,omitempty" yaml:"consumes,omitempty"`
+	Produces    []string     `json:"produces,omitempty" yaml:"produces,omitempty"`
+	ShadowedBy  string       `json:"shadowedBy,omitempty" yaml:"shadowedBy,omitempty"` // pattern of the route serving its paths, if it is unreachable
+}
+
+// RouteParam describes a path parameter of the route.
+type RouteParam struct {
+	Name   string `json:"name" yaml:"name"`
+	Regexp string `json:"regexp,omitempty" yaml:"regexp,omitempty"`
+}
+
+// RouteTable returns the snapshot of the routes, the implicit routes
+// such as HEAD of ServeMux style's GET are not included.
+func (r *Router) RouteTable() (table RouteTable) {
+	segs, fresh := r.splitRoutes()
+	for i := range r.routes {
+		rt := &r.routes[i]
+		if rt.fallback > 0 {
+			continue
+		}
+
+		info := r.routeInfo(rt)
+		e := RouteEntry{
+			Method:      rt.method,
+			Pattern:     rt.p.pattern,
+			Version:     info.Version,
+			OperationID: info.OperationID,
+			Consumes:    rt.consumes,
+			Produces:    rt.produces,
+		}
+
+		var key strings.Builder
+		for _, seg := range segs[i] {
+			key.WriteByte('/')
+			switch seg.Kind {
+			case LiteralSegment:
+				key.WriteString(seg.Text)
+				continue
+			case WildcardSegment:
+				key.WriteByte('*')
+			default:
+				key.WriteByte(':')
+			}
+			param := RouteParam{Name: rt.p.fields[len(e.Params)]}
+			if seg.Regexp != nil {
+				param.Regexp = seg.Regexp.String()
+			}
+			e.Params = append(e.Params, param)
+		}
+		key.WriteString(rt.p.verb)
+		e.Key = key.String()
+
+		for _, c := range rt.constraints {
+			if c.meta {
+				continue // listed as the metadata
+			}
+			e.Constraints = append(e.Constraints, c.String())
+		}
+		if pair, ok := r.unreachable(r.selectTree(rt.method), i, segs[i], fresh); ok {
+			e.ShadowedBy = pair.Winner.Pattern.pattern
+		}
+		table.Routes = append(table.Routes, e)
+	}
+
+	sort.SliceStable(table.Routes, func(i, j int) bool {
+		return table.Routes[i].less(&table.Routes[j])
+	})
+	return
+}
+
+func (e *RouteEntry) less(o *RouteEntry) bool {
+	if e.Key != o.Key {
+		return e.Key < o.Key
+	}
+	if e.Method != o.Method {
+		return e.Method < o.Method
+	}
+	if e.Version != o.Version {
+		return lessVersion(e.Version, o.Version)
+	}
+	return strings.Join(e.Constraints, "\n") < strings.Join(o.Constraints, "\n")
+}
+
+// RouteChange is a difference between two route tables.
+type RouteChange struct {
+	Method   string
+	Key      string
+	Version  string
+	Breaking bool   // the clients of the old routes may be broken
+	Message  string // such as "removed", `parameter "id" renamed to "user_id"`
+}
+
+// String returns the change as a line, such as "BREAKING GET /users/: removed".
+func (c RouteChange) String() string {
+	kind := "additive"
+	if c.Breaking {
+		kind = "BREAKING"
+	}
+	s := kind + " " + c.Method + " " + c.Key
+	if c.Version != "" {
+		s += " (" + c.Version + ")"
+	}
+	return s + ": " + c.Message
+}
+
+// DiffRouteTables returns the changes from the route table to the later one.
+//
+// The routes are identified by method, key and version. The removed routes,
+// the renamed parameters, the narrowed regular expressions, the added constraints,
+// the removed media types and the newly shadowed routes are breaking changes,
+// the others, such as the added routes and the widened regular expressions, are additive.
+//
+// A regular expression is narrowed if it is added, or some sample values of the old one
+// no longer match, the other changes of regular expressions are reported as additive.
+func DiffRouteTables(from, to RouteTable) (changes []RouteChange) {
+	type id struct{ method, key, version string }
+	olds := make(map[id][]RouteEntry)
+	for _, e := range from.Routes {
+		k := id{e.Method, e.Key, e.Version}
+		olds[k] = append(olds[k], e)
+	}
+
+	for _, e := range to.Routes {
+		k := id{e.Method, e.Key, e.Version}
+		candidates := olds[k]
+		if len(candidates) == 0 {
+			message := "added " + e.Pattern
+			if e.ShadowedBy != "" {
+				message += ", shadowed by " + e.ShadowedBy
+			}
+			changes = append(changes, e.change(false, message))
+			continue
+		}
+
+		// prefer the old route with the same constraints
+		match := 0
+		for i := range candidates {
+			if equalStrings(candidates[i].Constraints, e.Constraints) {
+				match = i
+				break
+			}
+		}
+		o := candidates[match]
+		olds[k] = append(candidates[:match:match], candidates[match+1:]...)
+		changes = append(changes, diffRouteEntry(&o, &e)...)
+	}
+
+	for _, e := range from.Routes {
+		k := id{e.Method, e.Key, e.Version}
+		if len(olds[k]) > 0 {
+			changes = append(changes, olds[k][0].change(true, "removed "+olds[k][0].Pattern))
+			olds[k] = olds[k][1:]
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := &changes[i], &changes[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return lessVersion(a.Version, b.Version)
+	})
+	return
+}
+
+// diffRouteEntry returns the changes of the route with the same identity.
+func diffRouteEntry(o, e *RouteEntry) (changes []RouteChange) {
+	for i := 0; i < len(e.Params) && i < len(o.Params); i++ {
+		op, np := o.Params[i], e.Params[i]
+		if op.Name != np.Name {
+			changes = append(changes, e.change(true, fmt.Sprintf("parameter %q renamed to %q", op.Name, np.Name)))
+		}
+		switch {
+		case op.Regexp == np.Regexp:
+		case op.Regexp == "":
+			changes = append(changes, e.change(true, fmt.Sprintf("parameter %q restricted to regexp %q",
+				np.Name, np.Regexp)))
+		case np.Regexp == "":
+			changes = append(changes, e.change(false, fmt.Sprintf("parameter %q no longer restricted to regexp %q",
+				np.Name, op.Regexp)))
+		case regexpNarrowed(op.Regexp, np.Regexp):
+			changes = append(changes, e.change(true, fmt.Sprintf("parameter %q regexp narrowed from %q to %q",
+				np.Name, op.Regexp, np.Regexp)))
+		default:
+			changes = append(changes, e.change(false, fmt.Sprintf("parameter %q regexp changed from %q to %q",
+				np.Name, op.Regexp, np.Regexp)))
+		}
+	}
+
+	if added := subtractStrings(e.Constraints, o.Constraints); len(added) > 0 {
+		changes = append(changes, e.change(true, "constraints added: "+strings.Join(added, ", ")))
+	}
+	if removed := subtractStrings(o.Constraints, e.Constraints); len(removed) > 0 {
+		changes = append(changes, e.change(false, "constraints removed: "+strings.Join(removed, ", ")))
+	}
+
+	changes = append(changes, diffMediaTypes(e, "consumes", o.Consumes, e.Consumes)...)
+	changes = append(changes, diffMediaTypes(e, "produces", o.Produces, e.Produces)...)
+
+	if o.OperationID != e.OperationID {
+		changes = append(changes, e.change(true, fmt.Sprintf("operation id changed from %q to %q",
+			o.OperationID, e.OperationID)))
+	}
+
+	switch {
+	case o.ShadowedBy == "" && e.ShadowedBy != "":
+		changes = append(changes, e.change(true, "shadowed by "+e.ShadowedBy))
+	case o.ShadowedBy != "" && e.ShadowedBy == "":
+		changes = append(changes, e.change(false, "no longer shadowed by "+o.ShadowedBy))
+	}
+	return
+}
+
+// regexpNarrowed reports whether some sample values matched by the regular expression from
+// are not matched by to.
+func regexpNarrowed(from, to string) bool {
+	re, err := regexp.Compile(to)
+	if err != nil {
+		return true
+	}
+	for _, s := range regexpSamples(from) {
+		if s != "" && !re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// lessVersion compares the versions of routes in the order of Version.Less,
+// the empty version is the first, the invalid ones are compared as strings.
+func lessVersion(a, b string) bool {
+	va, errA := ParseVersion(a)
+	vb, errB := ParseVersion(b)
+	if a == "" || b == "" || errA != nil || errB != nil {
+		return a < b
+	}
+	if va != vb {
+		return va.Less(vb)
+	}
+	return a < b
+}
+
+// diffMediaTypes returns the changes of the media types, the removed ones are breaking.
+func diffMediaTypes(e *RouteEntry, name string, from, to []string) (changes []RouteChange) {
+	if len(from) == 0 || len(to) == 0 {
+		// no media types means any
+		if len(from) == 0 && len(to) > 0 {
+			changes = append(changes, e.change(true, name+" restricted to "+strings.Join(to, ", ")))
+		} else if len(from) > 0 && len(to) == 0 {
+			changes = append(changes, e.change(false, name+" no longer restricted"))
+		}
+		return
+	}
+	if removed := subtractStrings(from, to); len(removed) > 0 {
+		changes = append(changes, e.change(true, name+" removed: "+strings.Join(removed, ", ")))
+	}
+	if added := subtractStrings(to, from); len(added) > 0 {
+		changes = append(changes, e.change(false, name+" added: "+strings.Join(added, ", ")))
+	}
+	return
+}
+
+func (e *RouteEntry) change(breaking bool, message string) RouteChange {
+	return RouteChange{Method: e.Method, Key: e.Key, Version: e.Version, Breaking: breaking, Message: message}
+}
+
+// subtractStrings returns the strings of a which are not in b.
+func subtractStrings(a, b []string) (diff []string) {
+	for _, s := range a {
+		found := false
+		for _, t := range b {
+			if s == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff = append(diff, s)
+		}
+	}
+	return
+}
+
+func equalStrings(a, b []string) bool {
+	return len(subtractStrings(a, b)) == 0 && len(subtractStrings(b, a)) == 0
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTable(t *testing.T) {
+	r := apirouter.New(
+		apirouter.GET(`/users/:id=^\d+$`, writeString("user"), apirouter.OperationID("getUser")),
+		apirouter.GET("/users/list", writeString("list"), apirouter.MatchHeader("X-Lang", "go")),
+		apirouter.GET("/about", writeString("about")),
+		apirouter.GET("/about", writeString("about2")),
+		apirouter.POST("/files/*path", writeString("file"), apirouter.Consumes("application/json")),
+	)
+
+	table := r.RouteTable()
+	data, err := json.Marshal(table)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"routes":[`+
+		`{"method":"GET","key":"/about","pattern":"/about","shadowedBy":"/about"},`+
+		`{"method":"GET","key":"/about","pattern":"/about"},`+
+		`{"method":"POST","key":"/files/*","pattern":"/files/*path","params":[{"name":"path"}],`+
+		`"consumes":["application/json"]},`+
+		`{"method":"GET","key":"/users/:","pattern":"/users/:id=^\\d+$","params":[{"name":"id","regexp":"^\\d+$"}],"operationId":"getUser"},`+
+		`{"method":"GET","key":"/users/list","pattern":"/users/list","constraints":["header X-Lang=go"]}]}`,
+		string(data))
+
+	var loaded apirouter.RouteTable
+	assert.NoError(t, json.Unmarshal(data, &loaded))
+	assert.Empty(t, apirouter.DiffRouteTables(loaded, table))
+}
+
+func TestDiffRouteTables(t *testing.T) {
+	old := apirouter.New(
+		apirouter.GET("/users/:id", writeString("user")),
+		apirouter.GET(`/items/:id=^\d+$`, writeString("item")),
+		apirouter.GET("/orders/:id", writeString("order"), apirouter.MatchQuery("v", "1")),
+		apirouter.POST("/orders", writeString("create"), apirouter.Consumes("application/json", "application/xml")),
+		apirouter.DELETE("/orders/:id", writeString("delete")),
+		apirouter.GET("/files/:name", writeString("file")),
+	).RouteTable()
+	cur := apirouter.New(
+		apirouter.GET("/users/:user_id", writeString("user")),
+		apirouter.GET(`/items/:id=^\w+$`, writeString("item")),
+		apirouter.GET("/orders/:id", writeString("order"), apirouter.MatchHeader("X-V", "1")),
+		apirouter.POST("/orders", writeString("create"), apirouter.Consumes("application/json", "text/csv")),
+		apirouter.GET("/files/:name", writeString("file")),
+		apirouter.GET(`/files/:name=^.+$`, writeString("regexp")),
+		apirouter.GET("/health", writeString("health")),
+	).RouteTable()
+
+	var lines []string
+	for _, c := range apirouter.DiffRouteTables(old, cur) {
+		lines = append(lines, c.String())
+	}
+	assert.Equal(t, []string{
+		`BREAKING GET /files/:: shadowed by /files/:name=^.+$`,
+		`additive GET /files/:: added /files/:name=^.+$`,
+		`additive GET /health: added /health`,
+		`additive GET /items/:: parameter "id" regexp changed from "^\\d+$" to "^\\w+$"`,
+		`BREAKING POST /orders: consumes removed: application/xml`,
+		`additive POST /orders: consumes added: text/csv`,
+		`BREAKING DELETE /orders/:: removed /orders/:id`,
+		`BREAKING GET /orders/:: constraints added: header X-V=1`,
+		`additive GET /orders/:: constraints removed: query v=1`,
+		`BREAKING GET /users/:: parameter "id" renamed to "user_id"`,
+	}, lines)
+}
+
+func TestDiffRouteTablesRegexps(t *testing.T) {
+	old := apirouter.New(
+		apirouter.GET(`/a/:id=^\w+$`, writeString("a")),
+		apirouter.GET(`/b/:id`, writeString("b")),
+		apirouter.GET(`/c/:id=^\d+$`, writeString("c")),
+		apirouter.GET(`/d/:id=^[a-z]+$`, writeString("d")),
+	).RouteTable()
+	cur := apirouter.New(
+		apirouter.GET(`/a/:id=^\d+$`, writeString("a")),
+		apirouter.GET(`/b/:id=^\d+$`, writeString("b")),
+		apirouter.GET(`/c/:id`, writeString("c")),
+		apirouter.GET(`/d/:id=^[a-z]{1,8}$`, writeString("d")),
+	).RouteTable()
+
+	var lines []string
+	for _, c := range apirouter.DiffRouteTables(old, cur) {
+		lines = append(lines, c.String())
+	}
+	assert.Equal(t, []string{
+		`BREAKING GET /a/:: parameter "id" regexp narrowed from "^\\w+$" to "^\\d+$"`,
+		`BREAKING GET /b/:: parameter "id" restricted to regexp "^\\d+$"`,
+		`additive GET /c/:: parameter "id" no longer restricted to regexp "^\\d+$"`,
+		`additive GET /d/:: parameter "id" regexp changed from "^[a-z]+$" to "^[a-z]{1,8}$"`,
+	}, lines)
+}
+
+func TestDiffRouteTablesVersions(t *testing.T) {
+	entry := func(version string) apirouter.RouteEntry {
+		return apirouter.RouteEntry{Method: "GET", Key: "/users", Pattern: "/users", Version: version}
+	}
+	old := apirouter.RouteTable{Routes: []apirouter.RouteEntry{entry("v10"), entry("v2"), entry("")}}
+	cur := apirouter.RouteTable{}
+
+	var versions []string
+	for _, c := range apirouter.DiffRouteTables(old, cur) {
+		versions = append(versions, c.Version)
+	}
+	assert.Equal(t, []string{"", "v2", "v10"}, versions)
+
+	table := apirouter.New(
+		apirouter.GET("/users", writeString("v10"), apirouter.ForVersion("v10")),
+		apirouter.GET("/users", writeString("v2"), apirouter.ForVersion("v2")),
+	).RouteTable()
+	if assert.Equal(t, 2, len(table.Routes)) {
+		assert.Equal(t, "v2", table.Routes[0].Version)
+		assert.Equal(t, "v10", table.Routes[1].Version)
+	}
+}
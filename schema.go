@@ -0,0 +1,267 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Schema is the JSON Schema of OpenAPI 3.1, only the commonly used keywords are supported.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty" yaml:"nullable,omitempty"` // encoded as type: [T, "null"]
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Not                  *Schema            `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, the boolean schemas and the type lists of
+// OpenAPI 3.1 are supported, such as "additionalProperties: false" and "type: [string, 'null']".
+func (s *Schema) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var allowed bool
+	if err := unmarshal(&allowed); err == nil {
+		*s = Schema{}
+		if !allowed {
+			s.Not = &Schema{}
+		}
+		return nil
+	}
+
+	type plain Schema
+	err := unmarshal((*plain)(s))
+	if terr, ok := err.(*yaml.TypeError); ok && len(terr.Errors) == 1 {
+		var list struct {
+			Type []string `yaml:"type"`
+		}
+		if unmarshal(&list) == nil && len(list.Type) > 0 {
+			for _, typ := range list.Type {
+				if typ == "null" {
+					s.Nullable = true
+				} else if s.Type == "" {
+					s.Type = typ
+				}
+			}
+			return nil
+		}
+	}
+	return err
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see UnmarshalYAML.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	return s.UnmarshalYAML(func(v interface{}) error { return yaml.Unmarshal(data, v) })
+}
+
+// MarshalJSON implements json.Marshaler, the nullable type is encoded as the type list
+// of OpenAPI 3.1, such as "type": ["string", "null"], instead of "nullable" of OpenAPI 3.0.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	v := struct {
+		Type interface{} `json:"type,omitempty"`
+		plain
+	}{plain: plain(s)}
+	if s.Type != "" {
+		v.Type = s.Type
+		if s.Nullable {
+			v.Type = []string{s.Type, "null"}
+		}
+	}
+	v.plain.Type, v.plain.Nullable = "", false
+	return json.Marshal(v)
+}
+
+// MarshalYAML implements yaml.Marshaler, see MarshalJSON.
+func (s Schema) MarshalYAML() (interface{}, error) {
+	data, err := s.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var m yaml.MapSlice // keeps the order of keys
+	err = yaml.Unmarshal(data, &m)
+	return m, err
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaGenerator generates the schemas of Go types by reflection,
+// the named struct types are generated into the components and referenced by "$ref".
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema of the type as encoding/json encodes it,
+// nil if the type can not be encoded.
+func (g *schemaGenerator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		return &Schema{} // unknown
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		min := float64(0)
+		return &Schema{Type: "integer", Minimum: &min}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		items := g.schemaOf(t.Elem())
+		if items == nil {
+			return nil
+		}
+		return &Schema{Type: "array", Items: items}
+	case reflect.Map:
+		values := g.schemaOf(t.Elem())
+		if values == nil {
+			return nil
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.nameOf(t)
+			g.names[t] = name
+			g.schemas[name] = nil // placeholder for the recursive types
+			g.schemas[name] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return nil
+}
+
+// nameOf returns the unique component name of the named type.
+func (g *schemaGenerator) nameOf(t reflect.Type) string {
+	name := sanitizeName(t.Name())
+	if _, taken := g.schemas[name]; taken {
+		name = sanitizeName(t.PkgPath()) + "." + name
+	}
+	return name
+}
+
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t, make(map[string]bool))
+	return s
+}
+
+// addFields adds the fields of struct into the schema, the names of the outer fields
+// are in the direct, the promoted fields are shadowed by them.
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type, direct map[string]bool) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if direct[name] {
+			continue
+		}
+
+		fs := g.schemaOf(f.Type)
+		if fs == nil {
+			continue
+		}
+		if strings.Contains(opts, ",string") && (fs.Type == "integer" || fs.Type == "number" || fs.Type == "boolean") {
+			fs = &Schema{Type: "string"}
+		}
+		direct[name] = true
+		s.Properties[name] = fs
+		if !strings.Contains(opts, ",omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	for _, et := range embedded {
+		g.addFields(s, et, direct)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import "regexp"
+
+// SegmentKind is the kind of path segment in pattern, see Pattern.Segments.
+type SegmentKind uint8
+
+const (
+	LiteralSegment  SegmentKind = iota // matches the text exactly
+	ParamSegment                       // matches any non-empty segment
+	RegexpSegment                      // matches the non-empty segment matched the regular expression
+	WildcardSegment                    // matches the rest of path, including the empty
+)
+
+var segmentKindNames = [...]string{"literal", "parameter", "regular expression parameter", "wildcard"}
+
+func (k SegmentKind) String() string {
+	if int(k) < len(segmentKindNames) {
+		return segmentKindNames[k]
+	}
+	return "unknown"
+}
+
+// PatternSegment is a parsed path segment of pattern, see Pattern.Segments.
+type PatternSegment struct {
+	Kind   SegmentKind
+	Text   string         // the text of literal segment
+	Regexp *regexp.Regexp // the regular expression of RegexpSegment
+}
+
+// Segments returns the path segments of the pattern, such as "/users/:id/*" has
+// a literal, a parameter and a wildcard segment. The verb is not included.
+func (p Pattern) Segments() []PatternSegment {
+	return splitKey(p, p.res)
+}
+
+// splitKey splits the key of pattern into segments,
+// res is the regular expressions shared by the patterns.
+// The verb of pattern is not included.
+func splitKey(p Pattern, res []*regexp.Regexp) []PatternSegment {
+	key := p.key[:len(p.key)-len(p.verb)]
+	var segs []PatternSegment
+	for i := 0; i < len(key); {
+		i++ // skip '/'
+		if i == len(key) {
+			segs = append(segs, PatternSegment{Kind: LiteralSegment})
+			break
+		}
+
+		switch key[i] {
+		case ':':
+			i++
+			if i+1 < len(key) && key[i] == '=' {
+				rec := int(key[i+1])
+				i += 2
+				var re *regexp.Regexp
+				if rec < len(res) {
+					re = res[rec]
+				}
+				segs = append(segs, PatternSegment{Kind: RegexpSegment, Regexp: re})
+			} else {
+				segs = append(segs, PatternSegment{Kind: ParamSegment})
+			}
+		case '*':
+			i = len(key)
+			segs = append(segs, PatternSegment{Kind: WildcardSegment})
+		default:
+			begin := i
+			for i < len(key) && key[i] != '/' {
+				i++
+			}
+			segs = append(segs, PatternSegment{Kind: LiteralSegment, Text: key[begin:i]})
+		}
+	}
+	return segs
+}
+
+// intersects reports whether some path segment matches both s and o.
+// Two regular expressions are assumed to intersect.
+func (s PatternSegment) intersects(o PatternSegment) bool {
+	if s.Kind == LiteralSegment && o.Kind == LiteralSegment {
+		return s.Text == o.Text
+	}
+	if s.Kind == LiteralSegment {
+		s, o = o, s
+	}
+	if o.Kind == LiteralSegment {
+		return s.matchString(o.Text)
+	}
+	return true
+}
+
+// contains reports whether all path segments matching o also match s.
+func (s PatternSegment) contains(o PatternSegment) bool {
+	switch s.Kind {
+	case LiteralSegment:
+		return o.Kind == LiteralSegment && s.Text == o.Text
+	case ParamSegment:
+		return o.Kind != LiteralSegment || o.Text != ""
+	case RegexpSegment:
+		if o.Kind == LiteralSegment {
+			return s.matchString(o.Text)
+		}
+		return o.Kind == RegexpSegment && s.Regexp != nil && o.Regexp != nil && s.Regexp.String() == o.Regexp.String()
+	}
+	return false
+}
+
+// matchString reports whether the single segment text matches s.
+func (s PatternSegment) matchString(text string) bool {
+	switch s.Kind {
+	case LiteralSegment:
+		return s.Text == text
+	case ParamSegment:
+		return text != ""
+	case RegexpSegment:
+		return text != "" && (s.Regexp == nil || s.Regexp.MatchString(text))
+	}
+	return true
+}
+
+// segmentsOverlap reports whether some path matches both a and b.
+func segmentsOverlap(a, b []PatternSegment) bool {
+	for i := 0; ; i++ {
+		if i == len(a) || i == len(b) {
+			return len(a) == len(b)
+		}
+		// the wildcard matches one or more segments
+		if a[i].Kind == WildcardSegment || b[i].Kind == WildcardSegment {
+			return true
+		}
+		if !a[i].intersects(b[i]) {
+			return false
+		}
+	}
+}
+
+// segmentsContain reports whether all paths matching b also match a.
+func segmentsContain(a, b []PatternSegment) bool {
+	for i := 0; ; i++ {
+		if i == len(b) {
+			return i == len(a)
+		}
+		if i == len(a) {
+			return false
+		}
+		if a[i].Kind == WildcardSegment {
+			return true
+		}
+		if b[i].Kind == WildcardSegment || !a[i].contains(b[i]) {
+			return false
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// NewServeMuxPattern creates a Go 1.22 http.ServeMux style's new Pattern from the given original pattern.
+// "regexps" is a list of regular expressions shared between multiple patterns.
+//
+// The pattern only contains the path, the syntax of the pattern string is as follows:
+//
+// 	Pattern		= "/" Segments [ "/" ]
+// 	Segments	= Segment { "/" Segment }
+// 	Segment		= LITERAL | Wildcard
+// 	Wildcard	= "{" NAME [ "..." ] "}" | "{$}"
+//
+// A trailing slash matches all the paths with the prefix, "{$}" matches the end of path,
+// "{NAME...}" matches the rest of path and must be the last segment.
+func NewServeMuxPattern(pattern string, regexps *[]*regexp.Regexp) (p Pattern, err error) {
+	if !strings.HasPrefix(pattern, "/") {
+		err = fmt.Errorf("pattern no leading / - %q", pattern)
+		return
+	}
+	_, converted, err := ConvertServeMuxPattern(pattern)
+	if err != nil {
+		return
+	}
+	if p, err = NewPattern(converted, regexps); err != nil {
+		return
+	}
+	p.pattern = pattern
+	p.backtrack = true
+	return
+}
+
+// NewForServeMux returns a new Router,which is initialized with
+// the given options and Go 1.22 http.ServeMux pattern style.
+//
+// Like http.ServeMux, the most specific pattern wins,
+// and New panics if two patterns conflict, that is, they match some
+// common paths, but neither is more specific than the other. For example:
+//
+// 	/items/{id}   /items/new          no conflict, /items/new wins
+// 	/a/{x}        /{y}/b              conflict, both match /a/b
+//
+// The patterns with host take precedence over the patterns without host,
+// and they do not conflict with each other, see MuxHandle.
+//
+// The syntax of the pattern reference apirouter.NewServeMuxPattern,
+// see also MuxHandle for the patterns with method and host.
+func NewForServeMux(options ...Option) *Router {
+	r := &Router{
+		notFoundHandler: http.NotFoundHandler(),
+		newPattern:      NewServeMuxPattern,
+	}
+	r.get.servemux = true
+	r.post.servemux = true
+	r.delete.servemux = true
+	r.put.servemux = true
+	r.patch.servemux = true
+	r.head.servemux = true
+	r.connect.servemux = true
+	r.trace.servemux = true
+	r.options.servemux = true
+
+	for _, opt := range options {
+		opt.apply(r)
+	}
+	r.initTrees()
+	return r
+}
+
+// MuxHandle creates the option to register the handler with the
+// Go 1.22 http.ServeMux pattern "[METHOD ][HOST]/[PATH]", such as "GET example.com/items/{id}".
+//
+// Like http.ServeMux, the pattern without method matches all methods,
+// and GET also matches HEAD, but the pattern with exact method takes precedence.
+// The host is matched as a constraint of the route, see MatchHost,
+// the routes with the request host are matched before the others.
+// It is supposed to be used with NewForServeMux.
+func MuxHandle(pattern string, handler http.Handler, options ...RouteOption) Option {
+	if handler == nil {
+		panic("router: nil handler")
+	}
+	method, host, path, err := splitServeMuxPattern(pattern)
+	if err != nil {
+		panic(fmt.Errorf("router: %v", err))
+	}
+	if host != "" {
+		options = append([]RouteOption{MatchHost(host)}, options...)
+	}
+
+	var methods []string
+	switch method {
+	case "":
+		methods = []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPut, http.MethodPatch,
+			http.MethodHead, http.MethodConnect, http.MethodTrace, http.MethodOptions}
+	case http.MethodGet:
+		methods = []string{http.MethodGet, http.MethodHead}
+	default:
+		methods = []string{method}
+	}
+
+	return optionFunc(func(r *Router) {
+		for _, m := range methods {
+			opts := options
+			if m != method { // implicit method, the exact one takes precedence
+				level := uint8(2)
+				if method != "" { // HEAD of GET
+					level = 1
+				}
+				opts = append(options[:len(options):len(options)], fallbackRoute(level))
+			}
+			r.addRoute(m, path, NewServeMuxPattern, func(w http.ResponseWriter, req *http.Request, ps Params) {
+				if ps.Count() > 0 {
+					serveWithParams(handler, w, req, ps)
+				} else {
+					handler.ServeHTTP(w, req)
+				}
+			}, opts)
+		}
+	})
+}
+
+// MuxHandleFunc is similar to MuxHandle, it registers the handler function.
+func MuxHandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), options ...RouteOption) Option {
+	if handler == nil {
+		panic("router: nil handler")
+	}
+	return MuxHandle(pattern, http.HandlerFunc(handler), options...)
+}
+
+// MatchHost creates the route option which requires the
+// request host, without port, is equal to the given host.
+//
+// With NewForServeMux, like the host-qualified patterns of http.ServeMux, the routes
+// with host are matched before the others and take precedence over them,
+// and they do not conflict with the routes without host.
+func MatchHost(host string) RouteOption {
+	c := NewConstraint(func(r *http.Request) bool {
+		return strings.EqualFold(requestHost(r), host)
+	}, http.StatusNotFound)
+	c.desc = "host " + host
+	return routeOptionFunc(func(rt *route) {
+		rt.constraints = append(rt.constraints, c)
+		rt.host = strings.ToLower(host)
+	})
+}
+
+// requestHost returns the request host without port.
+func requestHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		return host
+	}
+	return r.Host
+}
+
+// splitHosts moves the routes with host into the trees of their hosts.
+func (t *tree) splitHosts(r *Router) {
+	routes := t.routes[:0]
+	for _, rt := range t.routes {
+		if rt.host == "" {
+			routes = append(routes, rt)
+			continue
+		}
+		ht := t.hosts[rt.host]
+		if ht == nil {
+			if t.hosts == nil {
+				t.hosts = make(map[string]*tree)
+			}
+			ht = &tree{servemux: true, sep: t.sep}
+			t.hosts[rt.host] = ht
+		}
+		rt.host = "" // matched in the tree of the host
+		ht.add(rt)
+	}
+	t.routes = routes
+
+	for _, ht := range t.hosts {
+		ht.res = t.res
+		ht.init(r)
+	}
+}
+
+// matchHost matches the path against the routes with the request host,
+// the params are reset if no route matches.
+func (t *tree) matchHost(req *http.Request, path string, params *Params) *route {
+	ht := t.hosts[strings.ToLower(requestHost(req))]
+	if ht == nil {
+		return nil
+	}
+	if rt := ht.match(path, params); rt != nil {
+		return rt
+	}
+	*params = Params{}
+	return nil
+}
+
+// fallbackRoute marks the route is less preferred than the others with the same pattern,
+// the greater level is less preferred.
+func fallbackRoute(level uint8) RouteOption {
+	return routeOptionFunc(func(rt *route) {
+		rt.fallback = level
+	})
+}
+
+// checkConflicts panics if two routes conflict.
+func (t *tree) checkConflicts() {
+	segs := make([][]PatternSegment, len(t.routes))
+	for i := range t.routes {
+		segs[i] = splitKey(t.routes[i].p, t.res)
+	}
+
+	for i := range t.routes {
+		for j := i + 1; j < len(t.routes); j++ {
+			a, b := &t.routes[i], &t.routes[j]
+			if a.key() == b.key() {
+				if a.fallback != b.fallback || len(a.constraints) > 0 || len(b.constraints) > 0 {
+					continue
+				}
+				panic(fmt.Errorf("router: pattern %q conflicts with pattern %q, they are registered twice",
+					b.p.pattern, a.p.pattern))
+			}
+			if segmentsOverlap(segs[i], segs[j]) &&
+				!segmentsContain(segs[i], segs[j]) && !segmentsContain(segs[j], segs[i]) {
+				panic(fmt.Errorf("router: pattern %q conflicts with pattern %q, both match some paths, "+
+					"but neither is more specific", b.p.pattern, a.p.pattern))
+			}
+		}
+	}
+}
+
+// ConvertServeMuxPattern converts the pattern of Go 1.22 http.ServeMux,
+// such as "GET /items/{id}", into the method and default style pattern of apirouter.
+//
+// The method is empty if the pattern matches all methods.
+// The conversion rules are as follows:
+//
+// 	ServeMux            apirouter
+// 	/items/{id}         /items/:id
+// 	/files/{path...}    /files/*path
+// 	/static/            /static/*
+// 	/static/{$}         /static/
+//
+// The pattern with host is not supported. Note that http.ServeMux
+// also serves HEAD requests with the GET pattern.
+func ConvertServeMuxPattern(s string) (method, pattern string, err error) {
+	method, host, path, err := splitServeMuxPattern(s)
+	if err != nil {
+		return
+	}
+	if host != "" {
+		err = fmt.Errorf("pattern with host is not supported - %q", s)
+		return
+	}
+
+	var b strings.Builder
+	segments := strings.Split(path[1:], "/")
+	for i, seg := range segments {
+		b.WriteByte('/')
+		last := i == len(segments)-1
+		switch {
+		case seg == "":
+			if !last {
+				err = fmt.Errorf("pattern include empty segment - %q", s)
+				return
+			}
+			b.WriteByte('*') // trailing slash matches all the paths with the prefix
+		case seg == "{$}":
+			if !last {
+				err = fmt.Errorf("{$} in pattern must is last segment - %q", s)
+				return
+			}
+		case seg[0] == '{':
+			name, multi, e := parseServeMuxWildcard(seg, s)
+			if e != nil {
+				err = e
+				return
+			}
+			if multi {
+				if !last {
+					err = fmt.Errorf("{%s...} in pattern must is last segment - %q", name, s)
+					return
+				}
+				b.WriteByte('*')
+			} else {
+				b.WriteByte(':')
+			}
+			b.WriteString(name)
+		default:
+			if strings.ContainsAny(seg, "{}") {
+				err = fmt.Errorf("pattern wildcard must be a full segment - %q", s)
+				return
+			}
+			if seg[0] == ':' || seg[0] == '*' {
+				err = fmt.Errorf("pattern segment can not begin with ':' or '*' - %q", s)
+				return
+			}
+			b.WriteString(seg)
+		}
+	}
+
+	pattern = b.String()
+	return
+}
+
+// splitServeMuxPattern splits the "[METHOD ][HOST]/[PATH]" pattern.
+func splitServeMuxPattern(s string) (method, host, path string, err error) {
+	rest := strings.TrimLeft(s, " \t")
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		method, rest = rest[:i], strings.TrimLeft(rest[i:], " \t")
+		if method == "" || strings.ContainsAny(method, "/{}") {
+			err = fmt.Errorf("pattern has invalid method - %q", s)
+			return
+		}
+	}
+
+	i := strings.IndexByte(rest, '/')
+	if i < 0 {
+		err = fmt.Errorf("pattern no leading / - %q", s)
+		return
+	}
+	host, path = rest[:i], rest[i:]
+	if strings.ContainsAny(host, "{}") {
+		err = fmt.Errorf("pattern host must not contain wildcards - %q", s)
+	}
+	return
+}
+
+// parseServeMuxWildcard parses the "{name}" or "{name...}" segment.
+func parseServeMuxWildcard(seg, pattern string) (name string, multi bool, err error) {
+	if len(seg) < 2 || seg[len(seg)-1] != '}' {
+		err = fmt.Errorf("pattern  lack of '}' - %q", pattern)
+		return
+	}
+	name = seg[1 : len(seg)-1]
+	if strings.HasSuffix(name, "...") {
+		name, multi = name[:len(name)-3], true
+	}
+	if name == "" || strings.ContainsAny(name, "{}/=:*") {
+		err = fmt.Errorf("pattern has invalid wildcard name - %q", pattern)
+	}
+	return
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertServeMuxPattern(t *testing.T) {
+	tests := []struct {
+		s       string
+		method  string
+		pattern string
+		wantErr bool
+	}{
+		{"GET /items/{id}", "GET", "/items/:id", false},
+		{"/items/{id}/parts/{part}", "", "/items/:id/parts/:part", false},
+		{"POST /files/{path...}", "POST", "/files/*path", false},
+		{"/static/", "", "/static/*", false},
+		{"/static/{$}", "", "/static/", false},
+		{"/", "", "/*", false},
+		{"/{$}", "", "/", false},
+		{"GET  /about", "GET", "/about", false},
+		{"example.com/items", "", "", true},
+		{"GET items", "", "", true},
+		{"/items/{id", "", "", true},
+		{"/items/x{id}", "", "", true},
+		{"/files/{path...}/x", "", "", true},
+		{"/a/{$}/b", "", "", true},
+		{"/a//b", "", "", true},
+		{"/a/{}", "", "", true},
+		{"/users/:id", "", "", true},
+		{"GET /files/*path", "", "", true},
+	}
+	for _, tt := range tests {
+		method, pattern, err := apirouter.ConvertServeMuxPattern(tt.s)
+		if tt.wantErr {
+			assert.Error(t, err, tt.s)
+			continue
+		}
+		if assert.NoError(t, err, tt.s) {
+			assert.Equal(t, tt.method, method, tt.s)
+			assert.Equal(t, tt.pattern, pattern, tt.s)
+		}
+	}
+
+	// the same as NewServeMuxPattern
+	_, _, err := apirouter.ConvertServeMuxPattern("/users/:id")
+	_, err2 := apirouter.NewServeMuxPattern("/users/:id", nil)
+	assert.EqualError(t, err, `pattern segment can not begin with ':' or '*' - "/users/:id"`)
+	assert.Equal(t, err, err2)
+}
+
+func TestNewForServeMux(t *testing.T) {
+	r := apirouter.NewForServeMux(
+		apirouter.GET("/items/{id}", writeString("item")),
+		apirouter.GET("/items/new", writeString("new")),
+		apirouter.GET("/items/{id}/parts/{part}", writeString("part")),
+		apirouter.GET("/a/b/{z}/d", writeString("abzd")),
+		apirouter.GET("/a/{x}/c", writeString("axc")),
+		apirouter.GET("/static/", writeString("static")),
+		apirouter.GET("/static/{$}", writeString("index")),
+		apirouter.GET("/files/{path...}", writeString("files")),
+		apirouter.GET("/", writeString("root")),
+	)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/items/1", "item:id=1"},
+		{"/items/new", "new"},
+		{"/items/new/parts/2", "part:id=new:part=2"},
+		{"/a/b/c", "axc:x=b"},
+		{"/a/b/x/d", "abzd:z=x"},
+		{"/static/", "index"},
+		{"/static/css/a.css", "static:=css/a.css"},
+		{"/files/", "files:path="},
+		{"/files/a/b", "files:path=a/b"},
+		{"/other", "root:=other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(r, "GET", tt.path)
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
+
+func TestNewForServeMuxConflicts(t *testing.T) {
+	assert.Panics(t, func() {
+		apirouter.NewForServeMux(
+			apirouter.GET("/a/{x}", writeString("a")),
+			apirouter.GET("/{y}/b", writeString("b")),
+		)
+	})
+	assert.Panics(t, func() {
+		apirouter.NewForServeMux(
+			apirouter.GET("/items/{id}", writeString("a")),
+			apirouter.GET("/items/{name}", writeString("b")),
+		)
+	})
+	assert.Panics(t, func() {
+		apirouter.NewForServeMux(apirouter.GET("/items/:id", writeString("a")))
+	})
+	assert.NotPanics(t, func() {
+		apirouter.NewForServeMux(
+			apirouter.GET("/a/{x}", writeString("a")),
+			apirouter.POST("/{y}/b", writeString("b")),
+		)
+	})
+}
+
+func TestMuxHandle(t *testing.T) {
+	handler := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s + ":" + r.Method + ":" + apirouter.PathParams(r.Context()).ByName("id")))
+		}
+	}
+	r := apirouter.NewForServeMux(
+		apirouter.MuxHandleFunc("GET /items/{id}", handler("get")),
+		apirouter.MuxHandleFunc("/items/{id}", handler("any")),
+		apirouter.MuxHandleFunc("HEAD /users/{id}", handler("head")),
+		apirouter.MuxHandleFunc("GET /users/{id}", handler("user")),
+		apirouter.MuxHandleFunc("GET example.com/hosts/{id}", handler("host")),
+		apirouter.MuxHandleFunc("GET /hosts/{id}", handler("hosts")),
+	)
+
+	assert.Equal(t, "get:GET:1", serve(r, "GET", "/items/1").Body.String())
+	assert.Equal(t, "get:HEAD:1", serve(r, "HEAD", "/items/1").Body.String())
+	assert.Equal(t, "any:POST:1", serve(r, "POST", "/items/1").Body.String())
+	assert.Equal(t, "head:HEAD:2", serve(r, "HEAD", "/users/2").Body.String())
+	assert.Equal(t, "user:GET:2", serve(r, "GET", "/users/2").Body.String())
+
+	req, _ := http.NewRequest("GET", "http://example.com:8080/hosts/3", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "host:GET:3", w.Body.String())
+	req.Host = "other.com"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "hosts:GET:3", w.Body.String())
+}
+
+// TestMuxHandleHostPrecedence checks the precedence of the host-qualified patterns of http.ServeMux,
+// which are matched before the patterns without host and do not conflict with them.
+func TestMuxHandleHostPrecedence(t *testing.T) {
+	handler := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s))
+		}
+	}
+	var r, intercepted *apirouter.Router
+	routes := []apirouter.Option{
+		apirouter.MuxHandleFunc("example.com/a/{x}", handler("host a")),
+		apirouter.MuxHandleFunc("/a/{x}", handler("a")),
+		apirouter.MuxHandleFunc("/x/{y}", handler("b")),
+		apirouter.MuxHandleFunc("example.com/static/", handler("host static")),
+		apirouter.MuxHandleFunc("/static/app.js", handler("app.js")),
+		apirouter.MuxHandleFunc("GET /items/{id}", handler("items")),
+		apirouter.MuxHandleFunc("api.example.com/items/{id}", handler("api items")),
+		apirouter.MuxHandleFunc("/", handler("root")),
+	}
+	assert.NotPanics(t, func() {
+		r = apirouter.NewForServeMux(routes...)
+		intercepted = apirouter.NewForServeMux(append(routes, apirouter.Interceptors(apirouter.NewInterceptor(
+			func(w http.ResponseWriter, r *http.Request) bool {
+				return apirouter.CurrentRoute(r.Context()) != nil
+			}, nil)))...)
+	})
+
+	tests := []struct {
+		host, method, path string
+		want               string
+	}{
+		{"example.com", "GET", "/a/1", "host a"},
+		{"EXAMPLE.COM:8080", "GET", "/a/1", "host a"},
+		{"other.com", "GET", "/a/1", "a"},
+		{"other.com", "GET", "/x/b", "b"},
+		// the host-qualified pattern wins, even if it is less specific
+		{"example.com", "GET", "/static/app.js", "host static"},
+		{"other.com", "GET", "/static/app.js", "app.js"},
+		// the host-qualified pattern wins, even if it matches all methods
+		{"api.example.com", "GET", "/items/1", "api items"},
+		{"example.com", "GET", "/items/1", "items"},
+		// fall back to the patterns without host
+		{"example.com", "GET", "/x/b", "b"},
+		{"example.com", "GET", "/", "root"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, tt.want, w.Body.String(), "%s %s%s", tt.method, tt.host, tt.path)
+		w = httptest.NewRecorder()
+		intercepted.ServeHTTP(w, req)
+		assert.Equal(t, tt.want, w.Body.String(), "intercepted %s %s%s", tt.method, tt.host, tt.path)
+	}
+
+	// the patterns with the same host still conflict
+	assert.Panics(t, func() {
+		apirouter.NewForServeMux(
+			apirouter.MuxHandleFunc("example.com/a/{x}", handler("a")),
+			apirouter.MuxHandleFunc("example.com/{y}/b", handler("b")),
+		)
+	})
+	assert.NotPanics(t, func() {
+		apirouter.NewForServeMux(
+			apirouter.MuxHandleFunc("example.com/a/{x}", handler("a")),
+			apirouter.MuxHandleFunc("/{y}/b", handler("b")),
+			apirouter.MuxHandleFunc("other.com/{y}/b", handler("b")),
+		)
+	})
+}
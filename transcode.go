@@ -0,0 +1,158 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// Transcode returns the handler which transcodes the HTTP/JSON request
+// to call the gRPC style function fn, per the google.api.http rule:
+//
+// 	- the path parameters are set to the fields of their names, such as "book.id";
+// 	- the body is decoded to the whole request if rule.Body is "*",
+// 	  or to the field named by rule.Body, or ignored if rule.Body is empty;
+// 	- the query parameters are set to the other fields, the repeated fields take
+// 	  the repeated parameters, and the nested fields are named like "a.b=c",
+// 	  the unknown parameters are ignored;
+// 	- the response, or its field named by rule.ResponseBody, is replied in JSON.
+//
+// The request and response are the Go structs of the protobuf messages,
+// they are encoded per the protobuf JSON mapping, see protojson.go for details.
+// It panics if the messages use the unsupported well-known types, such as Any and Struct.
+// The invalid requests are replied 400 (Bad Request) with the code "invalid_argument",
+// the bodies larger than DefaultMaxBodyBytes are replied 413 (Request Entity Too Large).
+// The errors are replied by the error handler of the router, see ErrorHandler,
+// which records them in HandleInfo.Err for the interceptors.
+//
+// A nil rule has no body. Example:
+//
+// 	apirouter.GRPCRoutes(methods, func(m *apirouter.RPCMethod, rule *apirouter.HTTPRule) apirouter.Handler {
+// 		switch m.Name {
+// 		case "GetBook":
+// 			return apirouter.Transcode(rule, svc.GetBook)
+// 		}
+// 		return nil
+// 	})
+func Transcode[Req, Resp any](rule *HTTPRule, fn func(ctx context.Context, req *Req) (*Resp, error)) Handler {
+	if fn == nil {
+		panic("router: nil function")
+	}
+	if t := reflect.TypeOf((*Req)(nil)).Elem(); t.Kind() != reflect.Struct {
+		panic("router: request type " + t.String() + " is not a struct")
+	}
+	checked := make(map[reflect.Type]bool)
+	for _, t := range []reflect.Type{reflect.TypeOf((*Req)(nil)), reflect.TypeOf((*Resp)(nil))} {
+		if err := checkProtoType(t, checked); err != nil {
+			panic("router: " + err.Error() + " in " + t.Elem().String())
+		}
+	}
+
+	var body, responseBody string
+	if rule != nil {
+		body, responseBody = rule.Body, rule.ResponseBody
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, ps Params) {
+		req := new(Req)
+		if err := transcodeRequest(w, r, ps, body, reflect.ValueOf(req).Elem()); err != nil {
+			if he, ok := err.(*HTTPError); ok {
+				replyError(w, r, he) // such as the body is too large
+				return
+			}
+			replyError(w, r, &HTTPError{
+				Status: http.StatusBadRequest,
+				Code:   "invalid_argument",
+				Detail: err.Error(),
+				Err:    err,
+			})
+			return
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			replyError(w, r, err)
+			return
+		}
+
+		if resp == nil {
+			resp = new(Resp)
+		}
+		v := reflect.ValueOf(resp)
+		if responseBody != "" {
+			var ok bool
+			if v, ok = protoFieldByPath(v, responseBody); !ok {
+				replyError(w, r, fmt.Errorf("unknown response body field %q", responseBody))
+				return
+			}
+		}
+		data, err := marshalProtoJSON(v)
+		if err != nil {
+			replyError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+// transcodeRequest builds the request message from the query, body and path, in that order.
+func transcodeRequest(w http.ResponseWriter, r *http.Request, ps Params, body string, req reflect.Value) error {
+	if body != "*" {
+		query := r.URL.Query()
+		names := make([]string, 0, len(query))
+		for name := range query {
+			names = append(names, name)
+		}
+		sort.Strings(names) // deterministic, the names of a field may be in both cases
+
+		for _, name := range names {
+			if f, ok := protoFieldByPath(req, name); ok {
+				if err := setProtoValues(f, query[name]); err != nil {
+					return fmt.Errorf("invalid query parameter %q", name)
+				}
+			}
+		}
+	}
+
+	if body != "" && r.Body != nil {
+		data, err := readBody(w, r.Body, 0)
+		if err != nil {
+			return err
+		}
+		if len(data) > 0 {
+			v := req
+			if body != "*" {
+				var ok bool
+				if v, ok = protoFieldByPath(req, body); !ok {
+					return fmt.Errorf("unknown body field %q", body)
+				}
+				v.Set(reflect.Zero(v.Type()))
+			}
+			if err = unmarshalProtoJSON(data, v); err != nil {
+				return fmt.Errorf("invalid body: %v", err)
+			}
+		}
+	}
+
+	for i := 0; i < ps.Count(); i++ {
+		name := ps.Name(i)
+		if name == "" {
+			continue // anonymous
+		}
+		f, ok := protoFieldByPath(req, name)
+		if !ok {
+			return fmt.Errorf("unknown path field %q", name)
+		}
+		if err := setProtoScalar(f, ps.Value(i)); err != nil {
+			return fmt.Errorf("invalid path parameter %q", name)
+		}
+	}
+	return nil
+}
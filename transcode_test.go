@@ -0,0 +1,287 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+type book struct {
+	Name      string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	PageCount int64    `protobuf:"varint,2,opt,name=page_count,json=pageCount,proto3" json:"page_count,omitempty"`
+	Tags      []string `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
+	Author    *author  `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
+	Cover     []byte   `protobuf:"bytes,5,opt,name=cover,proto3" json:"cover,omitempty"`
+	Rating    float64  `protobuf:"fixed64,6,opt,name=rating,proto3" json:"rating,omitempty"`
+}
+
+type author struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+type updateBookRequest struct {
+	ShelfID    int32    `protobuf:"varint,1,opt,name=shelf_id,json=shelfId,proto3" json:"shelf_id,omitempty"`
+	Book       *book    `protobuf:"bytes,2,opt,name=book,proto3" json:"book,omitempty"`
+	UpdateMask []string `protobuf:"bytes,3,rep,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+}
+
+type updateBookResponse struct {
+	Book    *book  `protobuf:"bytes,1,opt,name=book,proto3" json:"book,omitempty"`
+	Request string `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"`
+}
+
+func updateBook(ctx context.Context, req *updateBookRequest) (*updateBookResponse, error) {
+	if req.ShelfID == 0 {
+		return nil, apirouter.NewHTTPError(http.StatusNotFound, "not_found", "shelf 0")
+	}
+	data, _ := json.Marshal(req)
+	return &updateBookResponse{Book: req.Book, Request: string(data)}, nil
+}
+
+func TestTranscode(t *testing.T) {
+	r := apirouter.NewForGRPC(
+		apirouter.API("PATCH", "/v1/shelves/{shelf_id}/books/{book.name}",
+			apirouter.Transcode(&apirouter.HTTPRule{Body: "book"}, updateBook)),
+		apirouter.API("POST", "/v1/shelves/{shelfId}/books:update",
+			apirouter.Transcode(&apirouter.HTTPRule{Body: "*", ResponseBody: "book"}, updateBook)),
+		apirouter.API("GET", "/v1/shelves/{shelf_id}/books/{book.name}",
+			apirouter.Transcode(nil, updateBook)),
+	)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// body to the field, path overrides the body, query to the other fields
+	w := do("PATCH", "/v1/shelves/1/books/go?update_mask=tags&updateMask=author.name&book.tags=x",
+		`{"name":"x","pageCount":"300","tags":["a","b"],"author":{"name":"rob"},"cover":"AQI=","rating":"4.5"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"book":{"name":"go","pageCount":"300","tags":["a","b"],"author":{"name":"rob"},"cover":"AQI=","rating":4.5},`+
+		`"request":"{\"shelf_id\":1,\"book\":{\"name\":\"go\",\"page_count\":300,\"tags\":[\"a\",\"b\"],\"author\":{\"name\":\"rob\"},\"cover\":\"AQI=\",\"rating\":4.5},\"update_mask\":[\"author.name\",\"tags\"]}"}`,
+		w.Body.String())
+
+	// no body, nested and repeated query parameters, unknown parameters are ignored
+	w = do("GET", "/v1/shelves/2/books/go?book.page_count=10&book.author.name=ken&book.tags=a&book.tags=b&alt=json", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `{"name":"go","pageCount":"10","tags":["a","b"],"author":{"name":"ken"}}`,
+		strings.SplitN(strings.TrimPrefix(w.Body.String(), `{"book":`), `,"request"`, 2)[0])
+
+	// whole body, the response body field
+	w = do("POST", "/v1/shelves/3/books:update?update_mask=tags",
+		`{"shelf_id":9,"book":{"name":"go","page_count":7},"updateMask":["name"]}`)
+	assert.Equal(t, `{"name":"go","pageCount":"7"}`, w.Body.String())
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"PATCH", "/v1/shelves/x/books/go", "", 400},
+		{"GET", "/v1/shelves/1/books/go?book.page_count=x", "", 400},
+		{"PATCH", "/v1/shelves/1/books/go", `{"pages":1}`, 400},
+		{"PATCH", "/v1/shelves/1/books/go", `{"name":1}`, 400},
+		{"PATCH", "/v1/shelves/1/books/go", `{"name":`, 400},
+		{"PATCH", "/v1/shelves/0/books/go", "", 404},
+	}
+	for _, tt := range tests {
+		w := do(tt.method, tt.path, tt.body)
+		assert.Equal(t, tt.status, w.Code, tt.path+" "+tt.body)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	}
+	assert.Contains(t, do("PATCH", "/v1/shelves/1/books/go", `{"pages":1}`).Body.String(), `"invalid_argument"`)
+}
+
+// shelfKind is an enum like generated by protoc-gen-go, whose values are reflected by Descriptor.
+type shelfKind int32
+
+func (shelfKind) Descriptor() enumDescriptor {
+	return enumDescriptor{"SHELF_KIND_UNSPECIFIED", "PUBLIC", "PRIVATE"}
+}
+
+type enumDescriptor []string
+
+func (d enumDescriptor) Values() enumDescriptor        { return d }
+func (d enumDescriptor) Len() int                      { return len(d) }
+func (d enumDescriptor) Get(i int) enumValueDescriptor { return enumValueDescriptor{d[i], int32(i)} }
+
+type enumValueDescriptor struct {
+	name   string
+	number int32
+}
+
+func (d enumValueDescriptor) Name() string  { return d.name }
+func (d enumValueDescriptor) Number() int32 { return d.number }
+
+// messageDescriptor is the full name of the well-known type, returned by ProtoReflect.
+type messageDescriptor string
+
+func (d messageDescriptor) Descriptor() messageDescriptor { return d }
+func (d messageDescriptor) FullName() string              { return string(d) }
+
+type timestamp struct {
+	Seconds int64 `protobuf:"varint,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	Nanos   int32 `protobuf:"varint,2,opt,name=nanos,proto3" json:"nanos,omitempty"`
+}
+
+func (*timestamp) ProtoReflect() messageDescriptor { return "google.protobuf.Timestamp" }
+
+type duration struct {
+	Seconds int64 `protobuf:"varint,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	Nanos   int32 `protobuf:"varint,2,opt,name=nanos,proto3" json:"nanos,omitempty"`
+}
+
+func (*duration) ProtoReflect() messageDescriptor { return "google.protobuf.Duration" }
+
+type int64Value struct {
+	Value int64 `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (*int64Value) ProtoReflect() messageDescriptor { return "google.protobuf.Int64Value" }
+
+type anyMessage struct {
+	TypeURL string `protobuf:"bytes,1,opt,name=type_url,json=typeUrl,proto3" json:"type_url,omitempty"`
+	Value   []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (*anyMessage) ProtoReflect() messageDescriptor { return "google.protobuf.Any" }
+
+type listShelvesRequest struct {
+	Kind     shelfKind   `protobuf:"varint,1,opt,name=kind,proto3,enum=library.ShelfKind" json:"kind,omitempty"`
+	Kinds    []shelfKind `protobuf:"varint,2,rep,packed,name=kinds,proto3,enum=library.ShelfKind" json:"kinds,omitempty"`
+	Since    *timestamp  `protobuf:"bytes,3,opt,name=since,proto3" json:"since,omitempty"`
+	Timeout  *duration   `protobuf:"bytes,4,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	PageSize *int64Value `protobuf:"bytes,5,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+}
+
+func TestTranscodeEnumsAndWellKnownTypes(t *testing.T) {
+	echo := func(ctx context.Context, req *listShelvesRequest) (*listShelvesRequest, error) {
+		return req, nil
+	}
+	r := apirouter.NewForGRPC(
+		apirouter.API("GET", "/v1/shelves", apirouter.Transcode(nil, echo)),
+		apirouter.API("POST", "/v1/shelves", apirouter.Transcode(&apirouter.HTTPRule{Body: "*"}, echo)),
+	)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the enums by names or numbers, the well-known types from their string forms
+	w := do("GET", "/v1/shelves?kind=PRIVATE&kinds=1&kinds=PRIVATE&since=2020-01-02T03:04:05.5Z&timeout=1.5s&page_size=10", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `{"kind":"PRIVATE","kinds":["PUBLIC","PRIVATE"],"since":"2020-01-02T03:04:05.500Z","timeout":"1.500s","pageSize":"10"}`,
+		w.Body.String())
+
+	w = do("POST", "/v1/shelves", `{"kind":2,"kinds":["PUBLIC",5],"since":"2020-01-02T11:04:05.000001+08:00","timeout":"-0.000000001s","pageSize":"7"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `{"kind":"PRIVATE","kinds":["PUBLIC",5],"since":"2020-01-02T03:04:05.000001Z","timeout":"-0.000000001s","pageSize":"7"}`,
+		w.Body.String())
+
+	for _, tt := range []struct{ method, path, body string }{
+		{"GET", "/v1/shelves?kind=OTHER", ""},
+		{"GET", "/v1/shelves?since=2020-01-02", ""},
+		{"GET", "/v1/shelves?timeout=1.5", ""},
+		{"GET", "/v1/shelves?timeout=1.1234567891s", ""},
+		{"POST", "/v1/shelves", `{"kind":"OTHER"}`},
+		{"POST", "/v1/shelves", `{"since":1}`},
+		{"POST", "/v1/shelves", `{"timeout":{"seconds":1}}`},
+	} {
+		assert.Equal(t, 400, do(tt.method, tt.path, tt.body).Code, tt.path+" "+tt.body)
+	}
+
+	// the unsupported well-known types are rejected
+	assert.PanicsWithValue(t, "router: unsupported well-known type google.protobuf.Any in apirouter_test.shelfDetail",
+		func() {
+			apirouter.Transcode(nil, func(ctx context.Context, req *listShelvesRequest) (*shelfDetail, error) {
+				return nil, nil
+			})
+		})
+}
+
+type shelfDetail struct {
+	Detail *anyMessage `protobuf:"bytes,1,opt,name=detail,proto3" json:"detail,omitempty"`
+}
+
+type moveBookRequest struct {
+	Book  string `json:"book,omitempty"`
+	Shelf string `json:"shelf,omitempty"`
+}
+
+func TestTranscodeGRPCRoutes(t *testing.T) {
+	methods, _ := apirouter.ParseFileDescriptorSet(libraryDescriptorSet())
+	r := apirouter.New(apirouter.GRPCRoutes(methods,
+		func(m *apirouter.RPCMethod, rule *apirouter.HTTPRule) apirouter.Handler {
+			if m.Name != "MoveBook" {
+				return nil
+			}
+			return apirouter.Transcode(rule, func(ctx context.Context, req *moveBookRequest) (*updateBookResponse, error) {
+				return &updateBookResponse{Book: &book{Name: req.Book, Tags: []string{req.Shelf}}}, nil
+			})
+		}))
+
+	req := httptest.NewRequest("POST", "/v1/books/go:move", strings.NewReader(`{"shelf":"a","book":"x"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, `{"name":"go","tags":["a"]}`, w.Body.String())
+}
+
+func TestTranscodeErrors(t *testing.T) {
+	var observed error
+	limit := apirouter.DefaultMaxBodyBytes
+	apirouter.DefaultMaxBodyBytes = 16
+	defer func() { apirouter.DefaultMaxBodyBytes = limit }()
+
+	r := apirouter.NewForGRPC(
+		apirouter.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, "custom: "+err.Error(), http.StatusTeapot)
+		}),
+		apirouter.Interceptors(apirouter.PostInterceptorEx(
+			func(w http.ResponseWriter, r *http.Request, info *apirouter.HandleInfo) {
+				observed = info.Err
+			})),
+		apirouter.API("PATCH", "/v1/shelves/{shelf_id}/books/{book.name}",
+			apirouter.Transcode(&apirouter.HTTPRule{Body: "book"}, updateBook)),
+	)
+
+	do := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/v1/shelves/0/books/go", "")
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "custom: Not Found (not_found): shelf 0\n", w.Body.String())
+	assert.EqualError(t, observed, "Not Found (not_found): shelf 0")
+
+	w = do("/v1/shelves/1/books/go", `{"pageCount":"300"}`)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	if he, ok := observed.(*apirouter.HTTPError); assert.True(t, ok) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, he.Status)
+		assert.Equal(t, "body_too_large", he.Code)
+	}
+
+	// WriteProblem by default
+	w = httptest.NewRecorder()
+	apirouter.Transcode(nil, updateBook)(w, httptest.NewRequest("GET", "/", nil), apirouter.Params{})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+}
@@ -23,8 +23,21 @@ const (
 
 // route stores the route entry in the router
 type route struct {
-	p Pattern
-	h Handler
+	method       string
+	p            Pattern
+	h            Handler
+	constraints  []Constraint
+	version      *Version
+	interceptors []Interceptor
+	middlewares  []Middleware
+	fallback     uint8 // the greater is less preferred than the others with the same pattern
+	doc          *operationDoc
+	consumes     []string // media types of the request body, see Consumes
+	produces     []string // media types of the response, see Produces
+	info         *RouteInfo
+	candidates   []route // the routes merged into this one in dispatch order, see mergeRoutes
+	host         string  // the lower case host of the ServeMux style pattern, see MatchHost
+	id           int     // index of the value in Matcher
 }
 
 func (rt route) key() string { return rt.p.key }
@@ -52,39 +65,69 @@ type tree struct {
 
 	// static pattern is handled separately
 	// Learn from aero (https://github.com/aerogo/aero)
-	static      map[string]Handler
+	static      map[string]*route
 	canBeStatic [2048]bool
 
+	// supportVerb splits the verb from the path before matching, such as gRPC style.
 	supportVerb bool
+	// verbFallback splits the verb like supportVerb, but matches the full path
+	// if no route has the verb, so that the paths with ':' in the last segment
+	// are not broken by the routes with the verb of the other styles.
+	verbFallback bool
+
+	// servemux enables the http.ServeMux semantics, the most specific pattern wins,
+	// the conflicting patterns are not allowed.
+	servemux bool
+	// hosts are the trees of the host-qualified routes of ServeMux style, keyed by host,
+	// which are matched before this tree.
+	hosts map[string]*tree
+
+	// sep is the segment separator, the default is '/'.
+	sep byte
+	// relative reports whether the paths have no leading separator,
+	// the keys still begin with the separator.
+	relative bool
 }
 
-func (t *tree) add(p Pattern, h Handler) {
-	if len(p.fields) == 0 { // static
-		if t.static == nil {
-			t.static = make(map[string]Handler)
-		}
-		t.static[p.pattern] = h
-		t.canBeStatic[len(p.pattern)] = true
-	} else {
-		t.routes = append(t.routes, route{p, h})
-	}
+// add appends the route entry, which takes effect after init.
+func (t *tree) add(rt route) {
+	t.routes = append(t.routes, rt)
 }
 
-func (t *tree) staticMatch(path string) Handler {
-	if t.canBeStatic[len(path)] {
-		if h, found := t.static[path]; found {
-			return h
+func (t *tree) staticMatch(path string) *route {
+	if len(path) < len(t.canBeStatic) && t.canBeStatic[len(path)] {
+		if rt, found := t.static[path]; found {
+			return rt
 		}
 	}
 	return nil
 }
 
-func (t *tree) patternMatch(path string, params *Params) (h Handler) {
-	path, verb := path, ""
-	if t.supportVerb {
-		path, verb = splitURLPath(path)
+func (t *tree) patternMatch(path string, params *Params) *route {
+	return t.traceMatch(path, params, nil)
+}
+
+// traceMatch is the patternMatch recording the steps to tr, which is nil if not traced.
+func (t *tree) traceMatch(path string, params *Params, tr *Explanation) (rt *route) {
+	if t.servemux {
+		return t.backtrackMatch(path, params, tr)
+	}
+
+	if t.supportVerb || t.verbFallback {
+		if path, verb := splitURLPath(path); verb != "" || t.supportVerb {
+			if rt = t.verbMatch(path, verb, params, tr); rt != nil || t.supportVerb {
+				return
+			}
+			if tr != nil {
+				tr.trace(TraceStep{Segment: verb, Branch: "verb", Detail: "fall back to the full path"})
+			}
+		}
 	}
+	return t.verbMatch(path, "", params, tr)
+}
 
+// verbMatch matches the path without the verb and then the verb.
+func (t *tree) verbMatch(path, verb string, params *Params, tr *Explanation) (rt *route) {
 	state := rootState
 
 	lastStarState := -1 // last '*' state
@@ -92,11 +135,19 @@ func (t *tree) patternMatch(path string, params *Params) (h Handler) {
 	lastStarPcount := uint16(0)
 	pcount := uint16(0) // parameter count
 	sc := len(t.base)
+	sep := t.sep
+	start := 0
+	if t.relative {
+		start = -1 // the leading separator is implied
+	}
 OUTER:
-	for i := 0; i < len(path); {
+	for i := start; i < len(path); {
 		// try to match the beginning '/' of current segment
-		slashState := t.base[state] + code('/')
+		slashState := t.base[state] + code(sep)
 		if !(slashState < sc && state == t.check[slashState]) {
+			if tr != nil {
+				tr.trace(TraceStep{Segment: path[i+1:], Branch: "literal", Detail: "no pattern has more segments"})
+			}
 			state = -1
 			break
 		}
@@ -113,7 +164,7 @@ OUTER:
 		}
 
 		// try to match current segment
-		for ; i < len(path) && path[i] != '/'; i++ {
+		for ; i < len(path) && path[i] != sep; i++ {
 			next := t.base[state] + code(path[i])
 			if next < sc && state == t.check[next] {
 				state = next
@@ -124,14 +175,22 @@ OUTER:
 			// try to match named parameter
 			next = t.base[slashState] + code(':')
 			if !(next < sc && slashState == t.check[next]) {
+				if tr != nil {
+					tr.traceLiteral(path, begin, i, sep)
+					tr.trace(TraceStep{Segment: path[begin:segmentEnd(path, i, sep)], Branch: "param", Detail: "no parameter"})
+				}
 				state = -1
 				break OUTER
 			}
 			state = next
+			if tr != nil {
+				tr.traceLiteral(path, begin, i, sep)
+				tr.trace(TraceStep{Segment: path[begin:segmentEnd(path, i, sep)], Branch: "param", OK: true})
+			}
 
 			// the ending / of segment
 			for ; i < len(path); i++ {
-				if path[i] == '/' {
+				if path[i] == sep {
 					break
 				}
 			}
@@ -139,7 +198,7 @@ OUTER:
 			// regular expression parameters are not required in most cases
 			if len(t.res) > 0 {
 				// try match regular expressions
-				state = t.matchReParam(state, sc, path[begin:i])
+				state = t.matchReParam(state, sc, path[begin:i], tr)
 			}
 
 			index := pcount << 1
@@ -148,13 +207,23 @@ OUTER:
 			pcount++
 			continue OUTER
 		}
+		if tr != nil {
+			tr.trace(TraceStep{Segment: path[begin:i], Branch: "literal", OK: true})
+		}
 	}
 
 	// If all other matching fail, try using * wildcard
 	if state == -1 {
 		if lastStarState == -1 {
+			if tr != nil {
+				tr.trace(TraceStep{Branch: "wildcard", Detail: "no wildcard to fall back"})
+			}
 			return
 		}
+		if tr != nil {
+			tr.trace(TraceStep{Segment: path[lastStarIndex:], Branch: "wildcard", OK: true,
+				Detail: "fall back to the last wildcard"})
+		}
 		pcount = lastStarPcount
 		index := pcount << 1
 		params.indices[index] = int16(lastStarIndex)
@@ -169,9 +238,15 @@ OUTER:
 			if next < sc && state == t.check[next] {
 				state = next
 			} else {
+				if tr != nil {
+					tr.trace(TraceStep{Segment: verb, Branch: "verb", Detail: "no pattern has the verb"})
+				}
 				return
 			}
 		}
+		if tr != nil {
+			tr.trace(TraceStep{Segment: verb, Branch: "verb", OK: true})
+		}
 	}
 
 	// get the end state
@@ -179,14 +254,132 @@ OUTER:
 	if endState < sc && t.check[endState] == state && t.base[endState] < 0 {
 		i := -t.base[endState] - 1
 		params.path = path
-		params.names = t.routes[i].p.fields
-		h = t.routes[i].h
+		rt = &t.routes[i]
+		params.names = rt.p.fields
+		rt.p.bindSpans(params)
+		if tr != nil {
+			tr.trace(TraceStep{Branch: "end", OK: true})
+		}
+	} else if tr != nil {
+		tr.trace(TraceStep{Branch: "end", Detail: "no pattern ends here"})
+	}
+	return
+}
+
+// backtrackMatch matches the path like patternMatch, but if the more specific branch fails,
+// it backtracks to try the less specific ones, so that the most specific pattern wins.
+func (t *tree) backtrackMatch(path string, params *Params, tr *Explanation) (rt *route) {
+	endState := t.walk(rootState, path, 0, 0, params, tr)
+	if endState < 0 {
+		return
 	}
+	rt = &t.routes[-t.base[endState]-1]
+	params.path = path
+	params.names = rt.p.fields
 	return
 }
 
+// walk returns the end state of path[i:] from the given state, or -1 if no match.
+// The branches are tried in the order of literal, regular expression parameter,
+// named parameter and wildcard.
+func (t *tree) walk(state int, path string, i int, pcount uint16, params *Params, tr *Explanation) int {
+	sc := len(t.base)
+	if i == len(path) { // get the end state
+		endState := t.base[state] + endCode
+		if endState < sc && t.check[endState] == state && t.base[endState] < 0 {
+			if tr != nil {
+				tr.trace(TraceStep{Branch: "end", OK: true})
+			}
+			return endState
+		}
+		if tr != nil {
+			tr.trace(TraceStep{Branch: "end", Detail: "no pattern ends here, backtrack"})
+		}
+		return -1
+	}
+
+	// the beginning '/' of current segment
+	slashState := t.base[state] + code(t.sep)
+	if !(slashState < sc && state == t.check[slashState]) {
+		if tr != nil {
+			tr.trace(TraceStep{Segment: path[i+1:], Branch: "literal", Detail: "no pattern has more segments, backtrack"})
+		}
+		return -1
+	}
+	begin := i + 1
+	end := begin // end index of current segment
+	for end < len(path) && path[end] != t.sep {
+		end++
+	}
+
+	// try literal
+	state = slashState
+	for i = begin; i < end; i++ {
+		next := t.base[state] + code(path[i])
+		if !(next < sc && state == t.check[next]) {
+			break
+		}
+		state = next
+	}
+	if i == end {
+		if tr != nil {
+			tr.trace(TraceStep{Segment: path[begin:end], Branch: "literal", OK: true})
+		}
+		if endState := t.walk(state, path, end, pcount, params, tr); endState >= 0 {
+			return endState
+		}
+	} else if tr != nil {
+		tr.traceLiteral(path, begin, i, t.sep)
+	}
+
+	// try parameters, which match the non-empty segment
+	paramState := t.base[slashState] + code(':')
+	if end > begin && paramState < sc && slashState == t.check[paramState] {
+		index := pcount << 1
+		params.indices[index] = int16(begin)
+		params.indices[index+1] = int16(end)
+
+		reState := t.base[paramState] + code('=')
+		if len(t.res) > 0 && reState < sc && paramState == t.check[reState] {
+			for j := 0; j < len(t.res); j++ {
+				next := t.base[reState] + j + codeOffset
+				if next >= sc {
+					break
+				}
+				if reState == t.check[next] && t.matchRegexp(j, path[begin:end], tr) {
+					if endState := t.walk(next, path, end, pcount+1, params, tr); endState >= 0 {
+						return endState
+					}
+				}
+			}
+		}
+		if tr != nil {
+			tr.trace(TraceStep{Segment: path[begin:end], Branch: "param", OK: true})
+		}
+		if endState := t.walk(paramState, path, end, pcount+1, params, tr); endState >= 0 {
+			return endState
+		}
+	}
+
+	// try wildcard, which matches the rest of path
+	starState := t.base[slashState] + code('*')
+	if starState < sc && slashState == t.check[starState] {
+		endState := t.base[starState] + endCode
+		if endState < sc && t.check[endState] == starState && t.base[endState] < 0 {
+			if tr != nil {
+				tr.trace(TraceStep{Segment: path[begin:], Branch: "wildcard", OK: true})
+			}
+			index := pcount << 1
+			params.indices[index] = int16(begin)
+			params.indices[index+1] = int16(len(path))
+			return endState
+		}
+	}
+	return -1
+}
+
 // regular expressions parameter include ':' + res[index]
-func (t *tree) matchReParam(state, sc int, segment string) int {
+func (t *tree) matchReParam(state, sc int, segment string, tr *Explanation) int {
 	next := t.base[state] + code('=')
 	if next < sc && state == t.check[next] {
 		reState := next
@@ -197,7 +390,7 @@ func (t *tree) matchReParam(state, sc int, segment string) int {
 				break
 			}
 			if reState == t.check[next] { // exist  parameter reg expressions
-				if t.res[j].MatchString(segment) {
+				if t.matchRegexp(j, segment, tr) {
 					state = next // ok
 					break
 				}
@@ -207,19 +400,60 @@ func (t *tree) matchReParam(state, sc int, segment string) int {
 	return state
 }
 
-// match returns the handler and path parameters that matches the given path.
-func (t *tree) match(path string, params *Params) (h Handler) {
-	if t.canBeStatic[len(path)] {
-		if handler, found := t.static[path]; found {
-			return handler
+// matchRegexp reports whether the segment matches the j'th regular expression.
+func (t *tree) matchRegexp(j int, segment string, tr *Explanation) bool {
+	ok := t.res[j].MatchString(segment)
+	if tr != nil {
+		tr.trace(TraceStep{Segment: segment, Branch: "regexp", OK: ok, Detail: t.res[j].String()})
+	}
+	return ok
+}
+
+// match returns the route and path parameters that matches the given path.
+func (t *tree) match(path string, params *Params) *route {
+	if len(path) < len(t.canBeStatic) && t.canBeStatic[len(path)] {
+		if rt, found := t.static[path]; found {
+			return rt
 		}
 	}
 	return t.patternMatch(path, params)
 }
 
-func (t *tree) init() {
+func (t *tree) init(r *Router) {
+	if t.sep == 0 {
+		t.sep = '/'
+	}
+	if t.servemux {
+		t.splitHosts(r)
+		t.checkConflicts()
+	}
+
 	// sort and de-duplicate
-	t.rearrange()
+	t.rearrange(r)
+
+	// static pattern is handled separately
+	var statics []route
+	routes := t.routes[:0]
+	for _, rt := range t.routes {
+		if len(rt.p.fields) == 0 {
+			statics = append(statics, rt)
+		} else {
+			routes = append(routes, rt)
+		}
+	}
+	t.routes = routes
+	for i := range statics {
+		if t.static == nil {
+			t.static = make(map[string]*route)
+		}
+		key := statics[i].p.key
+		if t.relative {
+			key = key[1:]
+		}
+		t.static[key] = &statics[i]
+		t.canBeStatic[len(key)] = true
+	}
+
 	t.grow((len(t.routes) + 1) * 2)
 	if len(t.routes) == 0 {
 		return
@@ -262,18 +496,23 @@ func (t *tree) init() {
 	}
 }
 
-func (t *tree) rearrange() {
-	sort.Slice(t.routes, func(i, j int) bool {
+func (t *tree) rearrange(r *Router) {
+	// stable, the routes with the same key keep the registration order
+	sort.SliceStable(t.routes, func(i, j int) bool {
 		return t.routes[i].key() < t.routes[j].key()
 	})
 
 	// de-duplicate
-	for i := len(t.routes) - 1; i > 0; i-- {
-		if t.routes[i].key() == t.routes[i-1].key() {
-			copy(t.routes[i-1:], t.routes[i:])
-			t.routes = t.routes[:len(t.routes)-1]
+	routes := t.routes[:0]
+	for i := 0; i < len(t.routes); {
+		j := i + 1
+		for j < len(t.routes) && t.routes[j].key() == t.routes[i].key() {
+			j++
 		}
+		routes = append(routes, mergeRoutes(t.routes[i:j], r))
+		i = j
 	}
+	t.routes = routes
 }
 
 func (t *tree) grow(n int) int {
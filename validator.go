@@ -0,0 +1,617 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Violation is a violation of the request or response to the OpenAPI schemas.
+type Violation struct {
+	In      string `json:"in"`             // "path", "query", "header", "body" or "response"
+	Name    string `json:"name,omitempty"` // parameter name, or JSON pointer of the body
+	Message string `json:"message"`
+}
+
+// ValidationError lists all the violations of the request or response.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error implements error.Error.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("validation failed")
+	for i, v := range e.Violations {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(v.In)
+		if v.Name != "" {
+			b.WriteString(" " + v.Name)
+		}
+		b.WriteString(" " + v.Message)
+	}
+	return b.String()
+}
+
+// ValidatorOption configures the validator created by NewValidator.
+type ValidatorOption interface {
+	applyValidator(*validator)
+}
+
+type validatorOptionFunc func(*validator)
+
+func (f validatorOptionFunc) applyValidator(v *validator) {
+	f(v)
+}
+
+// ValidationErrorHandler creates the validator option to set the handler which
+// replies the request failed the validation, the error is a *HTTPError wrapping the *ValidationError.
+// The default is the error handler of the router, see ErrorHandler.
+func ValidationErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) ValidatorOption {
+	if handler == nil {
+		panic("router: nil handler")
+	}
+	return validatorOptionFunc(func(v *validator) {
+		v.errorHandler = handler
+	})
+}
+
+// ValidateResponses creates the validator option to validate the JSON responses too,
+// the *ValidationError is reported after the response is written.
+// It is supposed to be used in test mode, since the response body is buffered.
+func ValidateResponses(report func(r *http.Request, err error)) ValidatorOption {
+	if report == nil {
+		panic("router: nil report function")
+	}
+	return validatorOptionFunc(func(v *validator) {
+		v.report = report
+	})
+}
+
+// ValidationBodyLimit creates the validator option to limit the JSON request body read by the validator,
+// the larger bodies are replied with 413 (Request Entity Too Large). The default is DefaultMaxBodyBytes.
+func ValidationBodyLimit(n int64) ValidatorOption {
+	if n <= 0 {
+		panic("router: body limit must be positive")
+	}
+	return validatorOptionFunc(func(v *validator) {
+		v.bodyLimit = n
+	})
+}
+
+// NewValidator returns an Interceptor which validates the path parameters, query parameters,
+// headers and JSON request body against the OpenAPI operation of the route before the handler runs.
+// The request failed the validation is replied with 400 (Bad Request) listing all the violations,
+// and the request body larger than the limit is replied with 413, see ValidationBodyLimit.
+//
+// The routes are associated with the operations by operationId, see OperationID and OpenAPIRoutes.
+// The validator must be registered by the global Interceptors option,
+// which makes the current route available. The schemas are compiled once.
+func NewValidator(doc *OpenAPI, options ...ValidatorOption) (Interceptor, error) {
+	v := &validator{
+		ops:          make(map[string]*operationSchema),
+		errorHandler: replyError,
+	}
+	for _, opt := range options {
+		opt.applyValidator(v)
+	}
+
+	c := &schemaCompiler{doc: doc, compiled: make(map[*Schema]*compiledSchema)}
+	for _, item := range doc.Paths {
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op := item.Operation(method)
+			if op == nil || op.OperationID == "" {
+				continue
+			}
+			opSchema, err := c.compileOperation(op, mergeParameters(item.Parameters, op.Parameters))
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema of operation %q - %v", op.OperationID, err)
+			}
+			v.ops[op.OperationID] = opSchema
+		}
+	}
+
+	if v.report != nil {
+		return &responseValidator{v}, nil
+	}
+	return v, nil
+}
+
+type validator struct {
+	ops          map[string]*operationSchema
+	errorHandler func(w http.ResponseWriter, r *http.Request, err error)
+	report       func(r *http.Request, err error)
+	bodyLimit    int64 // DefaultMaxBodyBytes if 0
+}
+
+// operationSchema is the compiled schemas of operation.
+type operationSchema struct {
+	params       []paramSchema
+	body         *compiledSchema // JSON request body, nil if not validated
+	bodyRequired bool
+	responses    map[string]*compiledSchema // JSON responses by status code, "default" or "2XX"
+}
+
+type paramSchema struct {
+	name     string
+	in       string
+	required bool
+	schema   *compiledSchema
+}
+
+func (v *validator) operation(r *http.Request) *operationSchema {
+	info := CurrentRoute(r.Context())
+	if info == nil || info.OperationID == "" {
+		return nil
+	}
+	return v.ops[info.OperationID]
+}
+
+// PreHandle implements Interceptor.PreHandle.
+func (v *validator) PreHandle(w http.ResponseWriter, r *http.Request) bool {
+	opSchema := v.operation(r)
+	if opSchema == nil {
+		return true
+	}
+
+	var vs []Violation
+	for _, p := range opSchema.params {
+		var values []string
+		switch p.in {
+		case "path":
+			if ps := PathParams(r.Context()); ps != nil {
+				values = []string{ps.ByName(p.name)}
+			}
+		case "query":
+			values = r.URL.Query()[p.name]
+		case "header":
+			values = r.Header[p.name]
+		case "cookie":
+			if c, err := r.Cookie(p.name); err == nil {
+				values = []string{c.Value}
+			}
+		}
+		if len(values) == 0 {
+			if p.required {
+				vs = append(vs, Violation{p.in, p.name, "is required"})
+			}
+			continue
+		}
+		if p.schema != nil {
+			p.schema.validate(p.schema.coerce(values), p.in, p.name, &vs)
+		}
+	}
+
+	if opSchema.body != nil && isJSON(r.Header.Get("Content-Type")) {
+		data, err := readBody(w, r.Body, v.bodyLimit)
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(data))
+		var he *HTTPError
+		switch {
+		case errors.As(err, &he):
+			v.errorHandler(w, r, err)
+			return false
+		case err != nil:
+			vs = append(vs, Violation{"body", "", "can not be read"})
+		case len(bytes.TrimSpace(data)) == 0:
+			if opSchema.bodyRequired {
+				vs = append(vs, Violation{"body", "", "is required"})
+			}
+		default:
+			var value interface{}
+			if err := json.Unmarshal(data, &value); err != nil {
+				vs = append(vs, Violation{"body", "", "is not valid JSON"})
+			} else {
+				opSchema.body.validate(value, "body", "", &vs)
+			}
+		}
+	} else if opSchema.bodyRequired && r.ContentLength == 0 && r.Header.Get("Content-Type") == "" {
+		vs = append(vs, Violation{"body", "", "is required"})
+	}
+
+	if len(vs) == 0 {
+		return true
+	}
+	verr := &ValidationError{Violations: vs}
+	v.errorHandler(w, r, &HTTPError{
+		Status:  http.StatusBadRequest,
+		Code:    "validation_failed",
+		Detail:  "the request does not match the api schema",
+		Details: vs,
+		Err:     verr,
+	})
+	return false
+}
+
+// PostHandle implements Interceptor.PostHandle.
+func (v *validator) PostHandle(r *http.Request) {}
+
+// responseValidator also validates the responses.
+type responseValidator struct {
+	*validator
+}
+
+// PreHandle implements Interceptor.PreHandle, it buffers the response body.
+func (v *responseValidator) PreHandle(w http.ResponseWriter, r *http.Request) bool {
+	if rec, ok := w.(*responseRecorder); ok && v.operation(r) != nil {
+		rec.body = new(bytes.Buffer)
+	}
+	return v.validator.PreHandle(w, r)
+}
+
+// PostHandleEx implements PostHandlerEx.PostHandleEx.
+func (v *responseValidator) PostHandleEx(w http.ResponseWriter, r *http.Request, info *HandleInfo) {
+	rec, ok := w.(*responseRecorder)
+	opSchema := v.operation(r)
+	if !ok || rec.body == nil || opSchema == nil || info.Recovered != nil {
+		return
+	}
+
+	status := strconv.Itoa(info.Status)
+	schema, found := opSchema.responses[status]
+	if !found {
+		schema, found = opSchema.responses[status[:1]+"XX"]
+	}
+	if !found {
+		schema, found = opSchema.responses["default"]
+	}
+
+	var vs []Violation
+	switch {
+	case !found:
+		vs = append(vs, Violation{"response", "", "status " + status + " is not documented"})
+	case schema == nil || !isJSON(w.Header().Get("Content-Type")):
+	default:
+		var value interface{}
+		if err := json.Unmarshal(rec.body.Bytes(), &value); err != nil {
+			vs = append(vs, Violation{"response", "", "is not valid JSON"})
+		} else {
+			schema.validate(value, "response", "", &vs)
+		}
+	}
+	if len(vs) > 0 {
+		v.report(r, &ValidationError{Violations: vs})
+	}
+}
+
+func isJSON(contentType string) bool {
+	mt := parseMediaRange(contentType)
+	return mt.typ == "application" && (mt.subtype == "json" || strings.HasSuffix(mt.subtype, "+json"))
+}
+
+// jsonSchemaOf returns the schema of the JSON content.
+func jsonSchemaOf(content map[string]*MediaType) *Schema {
+	keys := make([]string, 0, len(content))
+	for ct := range content {
+		keys = append(keys, ct)
+	}
+	sort.Strings(keys)
+	for _, ct := range keys {
+		if isJSON(ct) && content[ct] != nil {
+			return content[ct].Schema
+		}
+	}
+	return nil
+}
+
+// compiledSchema is the Schema ready to validate the values,
+// the references are resolved, and the regular expressions are compiled.
+type compiledSchema struct {
+	typ          string
+	nullable     bool
+	format       string
+	enum         map[string]bool
+	pattern      *regexp.Regexp
+	minimum      *float64
+	maximum      *float64
+	minLength    *int
+	maxLength    *int
+	items        *compiledSchema
+	properties   map[string]*compiledSchema
+	required     []string
+	additional   *compiledSchema
+	noAdditional bool
+	not          *compiledSchema
+}
+
+type schemaCompiler struct {
+	doc      *OpenAPI
+	compiled map[*Schema]*compiledSchema
+}
+
+func (c *schemaCompiler) compileOperation(op *Operation, params []*Parameter) (*operationSchema, error) {
+	opSchema := &operationSchema{responses: make(map[string]*compiledSchema)}
+	for _, p := range params {
+		ps := paramSchema{name: p.Name, in: p.In, required: p.Required || p.In == "path"}
+		if p.In == "header" {
+			ps.name = http.CanonicalHeaderKey(p.Name)
+		}
+		var err error
+		if ps.schema, err = c.compile(p.Schema); err != nil {
+			return nil, err
+		}
+		opSchema.params = append(opSchema.params, ps)
+	}
+
+	if rb := op.RequestBody; rb != nil {
+		var err error
+		if opSchema.body, err = c.compile(jsonSchemaOf(rb.Content)); err != nil {
+			return nil, err
+		}
+		opSchema.bodyRequired = rb.Required
+	}
+	for status, resp := range op.Responses {
+		var schema *compiledSchema
+		if resp != nil {
+			var err error
+			if schema, err = c.compile(jsonSchemaOf(resp.Content)); err != nil {
+				return nil, err
+			}
+		}
+		opSchema.responses[strings.ToUpper(status)] = schema
+	}
+	return opSchema, nil
+}
+
+func (c *schemaCompiler) compile(s *Schema) (*compiledSchema, error) {
+	if s == nil {
+		return nil, nil
+	}
+	if s.Ref != "" {
+		resolved := c.doc.resolve(s)
+		if resolved == nil {
+			return nil, fmt.Errorf("unresolved reference - %q", s.Ref)
+		}
+		s = resolved
+	}
+	if cs, ok := c.compiled[s]; ok {
+		return cs, nil
+	}
+
+	cs := &compiledSchema{
+		typ:       s.Type,
+		nullable:  s.Nullable,
+		format:    s.Format,
+		minimum:   s.Minimum,
+		maximum:   s.Maximum,
+		minLength: s.MinLength,
+		maxLength: s.MaxLength,
+		required:  s.Required,
+	}
+	c.compiled[s] = cs // the recursive schemas
+
+	var err error
+	if s.Pattern != "" {
+		if cs.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern - %q", s.Pattern)
+		}
+	}
+	if len(s.Enum) > 0 {
+		cs.enum = make(map[string]bool, len(s.Enum))
+		for _, e := range s.Enum {
+			cs.enum[fmt.Sprint(e)] = true
+		}
+	}
+	if cs.items, err = c.compile(s.Items); err != nil {
+		return nil, err
+	}
+	if len(s.Properties) > 0 {
+		cs.properties = make(map[string]*compiledSchema, len(s.Properties))
+		for name, ps := range s.Properties {
+			if cs.properties[name], err = c.compile(ps); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if ap := s.AdditionalProperties; ap != nil {
+		if ap.Not != nil && isEmptySchema(ap.Not) {
+			cs.noAdditional = true
+		} else if cs.additional, err = c.compile(ap); err != nil {
+			return nil, err
+		}
+	}
+	if cs.not, err = c.compile(s.Not); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func isEmptySchema(s *Schema) bool {
+	return s.Ref == "" && s.Type == "" && len(s.Enum) == 0 && s.Pattern == "" && s.Not == nil &&
+		len(s.Properties) == 0 && s.Items == nil && s.Minimum == nil && s.Maximum == nil
+}
+
+// coerce converts the parameter values to the value of schema type.
+func (cs *compiledSchema) coerce(values []string) interface{} {
+	if cs.typ == "array" {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]interface{}, len(values))
+		for i, s := range values {
+			items[i] = s
+			if cs.items != nil {
+				items[i] = cs.items.coerce([]string{s})
+			}
+		}
+		return items
+	}
+
+	s := values[0]
+	switch cs.typ {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s // the type mismatches are reported by validate
+}
+
+// validate appends the violations of the value decoded by encoding/json.
+func (cs *compiledSchema) validate(value interface{}, in, name string, vs *[]Violation) {
+	fail := func(format string, args ...interface{}) {
+		*vs = append(*vs, Violation{in, name, fmt.Sprintf(format, args...)})
+	}
+
+	if value == nil {
+		if !cs.nullable && cs.typ != "" && cs.typ != "null" {
+			fail("must not be null")
+		}
+		return
+	}
+	if cs.not != nil {
+		var nvs []Violation
+		cs.not.validate(value, in, name, &nvs)
+		if len(nvs) == 0 {
+			fail("is not allowed")
+			return
+		}
+	}
+	if cs.enum != nil && !cs.enum[fmt.Sprint(value)] {
+		fail("must be one of the enumerated values")
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		if !cs.isType("string") {
+			fail("must be %s", cs.typ)
+			return
+		}
+		cs.validateString(v, fail)
+	case float64:
+		if !cs.isType("number") && !(cs.isType("integer") && v == math.Trunc(v)) {
+			fail("must be %s", cs.typ)
+			return
+		}
+		cs.validateNumber(v, fail)
+	case bool:
+		if !cs.isType("boolean") {
+			fail("must be %s", cs.typ)
+		}
+	case []interface{}:
+		if !cs.isType("array") {
+			fail("must be %s", cs.typ)
+			return
+		}
+		if cs.items != nil {
+			for i, item := range v {
+				cs.items.validate(item, in, name+"/"+strconv.Itoa(i), vs)
+			}
+		}
+	case map[string]interface{}:
+		if !cs.isType("object") {
+			fail("must be %s", cs.typ)
+			return
+		}
+		cs.validateObject(v, in, name, vs)
+	}
+}
+
+func (cs *compiledSchema) isType(typ string) bool {
+	return cs.typ == "" || cs.typ == typ || (typ == "integer" && cs.typ == "number")
+}
+
+// formatRes are the regular expressions of the string formats.
+var formatRes = map[string]*regexp.Regexp{
+	"uuid":  regexp.MustCompile(formatRegexps["uuid"]),
+	"email": regexp.MustCompile(`^[^@\s]+@[^@\s]+$`),
+}
+
+func (cs *compiledSchema) validateString(s string, fail func(string, ...interface{})) {
+	n := utf8.RuneCountInString(s)
+	if cs.minLength != nil && n < *cs.minLength {
+		fail("must be at least %d characters", *cs.minLength)
+	}
+	if cs.maxLength != nil && n > *cs.maxLength {
+		fail("must be at most %d characters", *cs.maxLength)
+	}
+	if cs.pattern != nil && !cs.pattern.MatchString(s) {
+		fail("must match the pattern %q", cs.pattern.String())
+	}
+	switch cs.format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			fail("must be a date-time")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			fail("must be a date")
+		}
+	default:
+		if re := formatRes[cs.format]; re != nil && !re.MatchString(s) {
+			fail("must be a %s", cs.format)
+		}
+	}
+}
+
+func (cs *compiledSchema) validateNumber(f float64, fail func(string, ...interface{})) {
+	if cs.minimum != nil && f < *cs.minimum {
+		fail("must be >= %v", *cs.minimum)
+	}
+	if cs.maximum != nil && f > *cs.maximum {
+		fail("must be <= %v", *cs.maximum)
+	}
+	switch cs.format {
+	case "int32":
+		if f < math.MinInt32 || f > math.MaxInt32 {
+			fail("must be an int32")
+		}
+	case "int64":
+		if f < math.MinInt64 || f > math.MaxInt64 {
+			fail("must be an int64")
+		}
+	}
+}
+
+func (cs *compiledSchema) validateObject(obj map[string]interface{}, in, name string, vs *[]Violation) {
+	for _, req := range cs.required {
+		if _, ok := obj[req]; !ok {
+			*vs = append(*vs, Violation{in, name + "/" + req, "is required"})
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if ps, ok := cs.properties[key]; ok {
+			if ps != nil {
+				ps.validate(obj[key], in, name+"/"+key, vs)
+			}
+			continue
+		}
+		if cs.noAdditional {
+			*vs = append(*vs, Violation{in, name + "/" + key, "is not allowed"})
+		} else if cs.additional != nil {
+			cs.additional.validate(obj[key], in, name+"/"+key, vs)
+		}
+	}
+}
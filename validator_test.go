@@ -0,0 +1,246 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+const inventory = `
+openapi: 3.1.0
+info:
+  title: Inventory
+  version: "1.0"
+paths:
+  /items/{id}:
+    get:
+      operationId: getItem
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema: {type: integer, minimum: 1}
+        - name: fields
+          in: query
+          schema:
+            type: array
+            items: {type: string, enum: [name, price]}
+        - name: X-Request-Id
+          in: header
+          required: true
+          schema: {type: string, format: uuid}
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Item"}
+        "404":
+          description: Not Found
+  /items:
+    post:
+      operationId: createItem
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Item"}
+      responses:
+        "201":
+          description: Created
+components:
+  schemas:
+    Item:
+      type: object
+      required: [name, price]
+      additionalProperties: false
+      properties:
+        name: {type: string, minLength: 1}
+        price: {type: number, minimum: 0}
+        tags:
+          type: array
+          items: {type: string}
+`
+
+type validationProblem struct {
+	Code    string                `json:"code"`
+	Details []apirouter.Violation `json:"details"`
+}
+
+func problemOf(t *testing.T, w *httptest.ResponseRecorder) (p validationProblem) {
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	return
+}
+
+func TestValidator(t *testing.T) {
+	doc, err := apirouter.LoadOpenAPI([]byte(inventory))
+	assert.NoError(t, err)
+	v, err := apirouter.NewValidator(doc)
+	assert.NoError(t, err)
+	routes, err := apirouter.OpenAPIRoutes(doc, map[string]apirouter.Handler{
+		"getItem":    writeString("get"),
+		"createItem": writeString("create"),
+	})
+	assert.NoError(t, err)
+	r := apirouter.New(apirouter.Interceptors(v), routes)
+
+	const id = "123e4567-e89b-12d3-a456-426614174000"
+	w := serve(r, "GET", "/items/1?fields=name,price", "X-Request-Id", id)
+	assert.Equal(t, "get:id=1", w.Body.String())
+
+	w = serve(r, "GET", "/items/0?fields=name,size", "X-Request-Id", "1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "validation_failed", problemOf(t, w).Code)
+	assert.Equal(t, []apirouter.Violation{
+		{In: "path", Name: "id", Message: "must be >= 1"},
+		{In: "query", Name: "fields/1", Message: "must be one of the enumerated values"},
+		{In: "header", Name: "X-Request-Id", Message: "must be a uuid"},
+	}, problemOf(t, w).Details)
+
+	w = serve(r, "GET", "/items/x")
+	assert.Equal(t, []apirouter.Violation{
+		{In: "path", Name: "id", Message: "must be integer"},
+		{In: "header", Name: "X-Request-Id", Message: "is required"},
+	}, problemOf(t, w).Details)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/items", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	w = post(`{"name": "pen", "price": 1.5, "tags": ["a"]}`)
+	assert.Equal(t, "create", w.Body.String())
+
+	w = post(`{"name": "", "tags": [1], "color": "red"}`)
+	assert.Equal(t, []apirouter.Violation{
+		{In: "body", Name: "/price", Message: "is required"},
+		{In: "body", Name: "/color", Message: "is not allowed"},
+		{In: "body", Name: "/name", Message: "must be at least 1 characters"},
+		{In: "body", Name: "/tags/0", Message: "must be string"},
+	}, problemOf(t, w).Details)
+
+	w = post(`{"name": `)
+	assert.Equal(t, []apirouter.Violation{{In: "body", Message: "is not valid JSON"}}, problemOf(t, w).Details)
+
+	w = serve(r, "POST", "/items")
+	assert.Equal(t, []apirouter.Violation{{In: "body", Message: "is required"}}, problemOf(t, w).Details)
+}
+
+func TestValidatorBody(t *testing.T) {
+	doc, _ := apirouter.LoadOpenAPI([]byte(inventory))
+	v, _ := apirouter.NewValidator(doc)
+	r := apirouter.New(
+		apirouter.Interceptors(v),
+		apirouter.POST("/items", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			// the body is still readable by the handler
+			data, _ := ioutil.ReadAll(r.Body)
+			w.Write(data)
+		}, apirouter.OperationID("createItem")),
+	)
+
+	req := httptest.NewRequest("POST", "/items", strings.NewReader(`{"name":"pen","price":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, `{"name":"pen","price":1}`, w.Body.String())
+}
+
+func TestValidatorBodyLimit(t *testing.T) {
+	doc, _ := apirouter.LoadOpenAPI([]byte(inventory))
+	v, _ := apirouter.NewValidator(doc, apirouter.ValidationBodyLimit(24))
+	r := apirouter.New(
+		apirouter.Interceptors(v),
+		apirouter.POST("/items", writeString("create"), apirouter.OperationID("createItem")),
+	)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/items", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, "create", post(`{"name":"pen","price":1}`).Body.String())
+	w := post(`{"name":"pencil","price":1}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "body_too_large", problemOf(t, w).Code)
+
+	// replied by the error handler of the router
+	r = apirouter.New(
+		apirouter.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, "custom: "+err.Error(), http.StatusTeapot)
+		}),
+		apirouter.Interceptors(v),
+		apirouter.POST("/items", writeString("create"), apirouter.OperationID("createItem")),
+	)
+	w = post(`{"name":"pencil","price":1}`)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Contains(t, w.Body.String(), "custom: Request Entity Too Large (body_too_large)")
+
+	assert.Panics(t, func() { apirouter.ValidationBodyLimit(0) })
+}
+
+func TestValidateResponses(t *testing.T) {
+	doc, _ := apirouter.LoadOpenAPI([]byte(inventory))
+	var reported []error
+	v, err := apirouter.NewValidator(doc, apirouter.ValidateResponses(func(r *http.Request, err error) {
+		reported = append(reported, err)
+	}))
+	assert.NoError(t, err)
+
+	r := apirouter.New(
+		apirouter.Interceptors(v),
+		apirouter.GET("/items/:id", func(w http.ResponseWriter, r *http.Request, ps apirouter.Params) {
+			w.Header().Set("Content-Type", "application/json")
+			switch ps.ByName("id") {
+			case "1":
+				io.WriteString(w, `{"name":"pen","price":1}`)
+			case "2":
+				io.WriteString(w, `{"name":"pen"}`)
+			default:
+				w.WriteHeader(http.StatusTeapot)
+			}
+		}, apirouter.OperationID("getItem")),
+	)
+
+	const id = "123e4567-e89b-12d3-a456-426614174000"
+	serve(r, "GET", "/items/1", "X-Request-Id", id)
+	assert.Empty(t, reported)
+	serve(r, "GET", "/items/2", "X-Request-Id", id)
+	serve(r, "GET", "/items/3", "X-Request-Id", id)
+	assert.Equal(t, []error{
+		&apirouter.ValidationError{Violations: []apirouter.Violation{
+			{In: "response", Name: "/price", Message: "is required"}}},
+		&apirouter.ValidationError{Violations: []apirouter.Violation{
+			{In: "response", Message: "status 418 is not documented"}}},
+	}, reported)
+}
+
+func TestNewValidatorInvalid(t *testing.T) {
+	doc, _ := apirouter.LoadOpenAPI([]byte(`
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+  /a:
+    get:
+      operationId: a
+      parameters:
+        - {name: q, in: query, schema: {$ref: "#/components/schemas/None"}}
+`))
+	_, err := apirouter.NewValidator(doc)
+	assert.Error(t, err)
+}
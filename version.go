@@ -0,0 +1,241 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is an API version, its text form is "MAJOR[.MINOR]"
+// with an optional "v" prefix, such as "v2", "2.1".
+type Version struct {
+	Major int
+	Minor int
+}
+
+// ParseVersion parses the text form of API version.
+func ParseVersion(s string) (v Version, err error) {
+	text := strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	major, minor := text, ""
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		major, minor = text[:i], text[i+1:]
+	}
+	if v.Major, err = strconv.Atoi(major); err != nil || v.Major < 0 {
+		return Version{}, fmt.Errorf("invalid api version - %q", s)
+	}
+	if minor != "" {
+		if v.Minor, err = strconv.Atoi(minor); err != nil || v.Minor < 0 {
+			return Version{}, fmt.Errorf("invalid api version - %q", s)
+		}
+	}
+	return v, nil
+}
+
+// MustVersion is like ParseVersion but panics if the version cannot be parsed.
+func MustVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(fmt.Sprintf("Version initialization failed: %v", err))
+	}
+	return v
+}
+
+// Less reports whether v is older than o.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	return v.Minor < o.Minor
+}
+
+// String returns the text form of the version, such as "v2", "v2.1".
+func (v Version) String() string {
+	if v.Minor == 0 {
+		return "v" + strconv.Itoa(v.Major)
+	}
+	return "v" + strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor)
+}
+
+// VersionSource extracts the requested API version from the HTTP request.
+type VersionSource interface {
+	// Version returns the requested version,
+	// or an empty string if the request does not specify one.
+	Version(r *http.Request) string
+}
+
+// VersionSourceFunc is an adapter to allow the use of
+// ordinary functions as VersionSource.
+type VersionSourceFunc func(r *http.Request) string
+
+// Version implements VersionSource.Version.
+func (f VersionSourceFunc) Version(r *http.Request) string {
+	return f(r)
+}
+
+// VersionFromHeader returns a VersionSource which extracts the
+// version from the given request header, such as "X-API-Version: 2".
+func VersionFromHeader(name string) VersionSource {
+	return VersionSourceFunc(func(r *http.Request) string {
+		return r.Header.Get(name)
+	})
+}
+
+// VersionFromQuery returns a VersionSource which extracts the
+// version from the given query parameter, such as "?api-version=2".
+func VersionFromQuery(name string) VersionSource {
+	return VersionSourceFunc(func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	})
+}
+
+// VersionFromAccept returns a VersionSource which extracts the
+// version from the given parameter of the Accept media types,
+// such as "Accept: application/json; version=2".
+func VersionFromAccept(param string) VersionSource {
+	param = strings.ToLower(param)
+	return VersionSourceFunc(func(r *http.Request) string {
+		accept := r.Header.Get("Accept")
+		if accept == "" {
+			return ""
+		}
+		for _, mr := range parseAccept(accept) {
+			if v, ok := mr.params[param]; ok {
+				return v
+			}
+		}
+		return ""
+	})
+}
+
+// VersionFromPath returns a VersionSource which extracts the
+// version from the first segment of the URL path, such as "/v2/users".
+//
+// The router matches the routes with the rest of the path,
+// so the patterns are registered without the version prefix.
+func VersionFromPath() VersionSource {
+	return pathVersionSource{}
+}
+
+type pathVersionSource struct{}
+
+func (pathVersionSource) Version(r *http.Request) string {
+	v, _ := splitVersionPrefix(r.URL.Path)
+	return v
+}
+
+// splitVersionPrefix splits the "/vN[.M]" prefix from the path.
+func splitVersionPrefix(path string) (version, rest string) {
+	if len(path) < 3 || path[0] != '/' || (path[1] != 'v' && path[1] != 'V') ||
+		path[2] < '0' || path[2] > '9' {
+		return "", path
+	}
+	end := strings.IndexByte(path[1:], '/') + 1
+	if end == 0 {
+		end = len(path)
+	}
+	if _, err := ParseVersion(path[1:end]); err != nil {
+		return "", path
+	}
+	rest = path[end:]
+	if rest == "" {
+		rest = "/"
+	}
+	return path[1:end], rest
+}
+
+// versioning is the API versioning settings of router.
+type versioning struct {
+	source      VersionSource
+	stripPrefix bool
+	deprecated  map[Version]deprecation
+}
+
+type deprecation struct {
+	date   time.Time
+	sunset time.Time
+}
+
+// Versioning creates the option to select the route version
+// from the given source.
+//
+// Routes are registered per version with the ForVersion route option,
+// the latest version not newer than the requested wins,
+// or the latest version if the request does not specify one.
+// A 400 Bad Request is replied if the requested version is invalid.
+func Versioning(source VersionSource) Option {
+	if source == nil {
+		panic("router: nil version source")
+	}
+
+	return optionFunc(func(r *Router) {
+		r.versioning.source = source
+		_, r.versioning.stripPrefix = source.(pathVersionSource)
+	})
+}
+
+// DeprecatedVersion creates the option to mark the given version as deprecated.
+//
+// Responses served by the version routes carry the "Deprecation" header,
+// and the "Sunset" header if the sunset is not zero.
+// The date is the deprecation date, zero means the version is deprecated now.
+func DeprecatedVersion(version string, date, sunset time.Time) Option {
+	v := MustVersion(version)
+	return optionFunc(func(r *Router) {
+		if r.versioning.deprecated == nil {
+			r.versioning.deprecated = make(map[Version]deprecation)
+		}
+		r.versioning.deprecated[v] = deprecation{date, sunset}
+	})
+}
+
+// ForVersion creates the route option to register the route
+// for the given API version.
+//
+// Routes of several versions may be registered with the same pattern.
+func ForVersion(version string) RouteOption {
+	v := MustVersion(version)
+	return routeOptionFunc(func(rt *route) {
+		rt.version = &v
+	})
+}
+
+// requestedVersion returns the requested version, nil means the latest.
+func (r *Router) requestedVersion(req *http.Request) (*Version, bool) {
+	if r == nil || r.versioning.source == nil {
+		return nil, true
+	}
+	s := r.versioning.source.Version(req)
+	if s == "" {
+		return nil, true
+	}
+	v, err := ParseVersion(s)
+	if err != nil {
+		return nil, false
+	}
+	return &v, true
+}
+
+// markVersion writes the deprecation headers of the served version.
+func (r *Router) markVersion(w http.ResponseWriter, v Version) {
+	if r == nil {
+		return
+	}
+	d, ok := r.versioning.deprecated[v]
+	if !ok {
+		return
+	}
+	if d.date.IsZero() {
+		w.Header().Set("Deprecation", "true")
+	} else {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.date.Unix(), 10))
+	}
+	if !d.sunset.IsZero() {
+		w.Header().Set("Sunset", d.sunset.UTC().Format(http.TimeFormat))
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2019,CAO HONGJU. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package apirouter_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cnotch/apirouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    apirouter.Version
+		wantErr bool
+	}{
+		{"v1", apirouter.Version{Major: 1}, false},
+		{"2", apirouter.Version{Major: 2}, false},
+		{"V2.1", apirouter.Version{Major: 2, Minor: 1}, false},
+		{"", apirouter.Version{}, true},
+		{"v", apirouter.Version{}, true},
+		{"v1.x", apirouter.Version{}, true},
+	}
+	for _, tt := range tests {
+		got, err := apirouter.ParseVersion(tt.s)
+		if tt.wantErr {
+			assert.Error(t, err, tt.s)
+			continue
+		}
+		assert.NoError(t, err, tt.s)
+		assert.Equal(t, tt.want, got)
+	}
+	assert.Equal(t, "v2.1", apirouter.MustVersion("2.1").String())
+}
+
+func versionedRoutes(options ...apirouter.Option) *apirouter.Router {
+	return apirouter.New(append(options,
+		apirouter.GET("/users/:id", writeString("v1"), apirouter.ForVersion("v1")),
+		apirouter.GET("/users/:id", writeString("v2"), apirouter.ForVersion("v2")),
+		apirouter.GET("/users/:id", writeString("v2.1"), apirouter.ForVersion("v2.1")),
+		apirouter.GET("/health", writeString("ok")),
+		apirouter.GET("/orders", writeString("v2"), apirouter.ForVersion("v2")),
+	)...)
+}
+
+func TestVersionFromHeader(t *testing.T) {
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := versionedRoutes(
+		apirouter.Versioning(apirouter.VersionFromHeader("X-API-Version")),
+		apirouter.DeprecatedVersion("v1", time.Time{}, sunset),
+	)
+
+	w := serve(r, "GET", "/users/1", "X-API-Version", "1")
+	assert.Equal(t, "v1:id=1", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Tue, 01 Jan 2030 00:00:00 GMT", w.Header().Get("Sunset"))
+
+	w = serve(r, "GET", "/users/1", "X-API-Version", "2")
+	assert.Equal(t, "v2:id=1", w.Body.String())
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	// latest compatible
+	assert.Equal(t, "v2.1:id=1", serve(r, "GET", "/users/1", "X-API-Version", "3").Body.String())
+	// latest
+	assert.Equal(t, "v2.1:id=1", serve(r, "GET", "/users/1").Body.String())
+	// unversioned
+	assert.Equal(t, "ok", serve(r, "GET", "/health", "X-API-Version", "1").Body.String())
+	// older than all
+	assert.Equal(t, http.StatusNotFound, serve(r, "GET", "/orders", "X-API-Version", "1").Code)
+	// invalid
+	assert.Equal(t, http.StatusBadRequest, serve(r, "GET", "/users/1", "X-API-Version", "x").Code)
+}
+
+func TestVersionFromQueryAndAccept(t *testing.T) {
+	r := versionedRoutes(apirouter.Versioning(apirouter.VersionFromQuery("api-version")))
+	assert.Equal(t, "v2:id=1", serve(r, "GET", "/users/1?api-version=2.0").Body.String())
+
+	r = versionedRoutes(apirouter.Versioning(apirouter.VersionFromAccept("version")))
+	assert.Equal(t, "v1:id=1", serve(r, "GET", "/users/1", "Accept", "application/json; version=1").Body.String())
+	assert.Equal(t, "v2.1:id=1", serve(r, "GET", "/users/1", "Accept", "application/json").Body.String())
+}
+
+func TestVersionFromPath(t *testing.T) {
+	r := versionedRoutes(apirouter.Versioning(apirouter.VersionFromPath()))
+	assert.Equal(t, "v1:id=1", serve(r, "GET", "/v1/users/1").Body.String())
+	assert.Equal(t, "v2:id=1", serve(r, "GET", "/v2/users/1").Body.String())
+	assert.Equal(t, "v2.1:id=1", serve(r, "GET", "/users/1").Body.String())
+	assert.Equal(t, "ok", serve(r, "GET", "/v1/health").Body.String())
+	assert.Equal(t, "ok", serve(r, "GET", "/health").Body.String())
+
+	h, ps := r.Match("GET", "/v2/users/9")
+	assert.NotNil(t, h)
+	assert.Equal(t, "9", ps.ByName("id"))
+}
+
+func TestCurrentRouteOfCandidate(t *testing.T) {
+	var pre, post []string
+	current := func(r *http.Request) string {
+		info := apirouter.CurrentRoute(r.Context())
+		if info.Version != "" {
+			return info.Version
+		}
+		if len(info.Constraints) > 0 {
+			return info.Constraints[0].String()
+		}
+		return "default"
+	}
+	r := versionedRoutes(
+		apirouter.Versioning(apirouter.VersionFromHeader("X-API-Version")),
+		apirouter.GET("/media", writeString("media"), apirouter.MatchQuery("alt", "media")),
+		apirouter.GET("/media", writeString("default")),
+		apirouter.Interceptors(apirouter.NewInterceptor(
+			func(w http.ResponseWriter, r *http.Request) bool {
+				pre = append(pre, apirouter.CurrentRoute(r.Context()).Pattern.Pattern())
+				return true
+			},
+			func(r *http.Request) {
+				post = append(post, current(r))
+			})),
+	)
+
+	serve(r, "GET", "/users/1", "X-API-Version", "1")
+	serve(r, "GET", "/users/1", "X-API-Version", "2")
+	serve(r, "GET", "/users/1")
+	serve(r, "GET", "/media?alt=media")
+	serve(r, "GET", "/media")
+	assert.Equal(t, []string{"/users/:id", "/users/:id", "/users/:id", "/media", "/media"}, pre)
+	assert.Equal(t, []string{"v1", "v2", "v2.1", "query alt=media", "default"}, post)
+
+	e := r.Explain("GET", "/users/1")
+	assert.Equal(t, "v2.1", e.Route.Version)
+	assert.Contains(t, e.String(), "\tcandidate \"\" ok, version v2.1\n\tcandidate \"\" ok, version v2\n")
+}